@@ -5,34 +5,70 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"os"
-	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/dstotijn/go-notion"
-	"github.com/sashabaranov/go-openai"
-	"github.com/slack-go/slack"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/queue"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/request"
 	"github.com/slack-go/slack/slackevents"
 )
 
 // Response is the response struct for the lambda function
-type Response events.APIGatewayProxyResponse
+type Response = events.APIGatewayProxyResponse
+
+var (
+	cfg      *config.Config
+	archiver *archive.Archiver
+	jobQueue queue.Queue
+)
 
 func main() {
-	lambda.Start(Handler)
+	cfg = config.MustLoad(config.SlackToken, config.SlackSigningSecret, config.NotionToken, config.NotionDatabase)
+
+	a, err := archive.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("[ERROR] %v", err)
+	}
+	archiver = a
+
+	q, err := queue.NewLambdaInvoke(lambdacontext.FunctionName)
+	if err != nil {
+		log.Fatalf("[ERROR] Failed to create job queue: %v", err)
+	}
+	jobQueue = q
+
+	lambda.Start(queue.LambdaHandler(JobHandler, Handler))
 }
 
-// Handler is the main function for the lambda function
-func Handler(r events.APIGatewayProxyRequest) (Response, error) {
+// JobHandler archives the thread of a queued Slack event
+func JobHandler(ctx context.Context, job queue.Job) error {
+	switch job.Kind {
+	case queue.KindSlackEvent:
+		return archiver.HandleEvent(ctx, job.Payload)
+	default:
+		log.Printf("[INFO] unknow job: %s", job.Kind)
+		return nil
+	}
+}
+
+// Handler acknowledges the Slack request and enqueues the event so that Slack gets a response within 3 seconds
+func Handler(ctx context.Context, r events.APIGatewayProxyRequest) (Response, error) {
+
+	body, err := request.Body(r)
+	if err != nil {
+		log.Printf("[ERROR] Failed to decode base64 encoded payload: %v", err)
+		return Response{StatusCode: 200}, nil
+	}
 
-	if err := slackRequestVerifier(r); err != nil {
+	if err := request.Verify(request.Header(r.Headers), body, cfg.SlackSigningSecret); err != nil {
 		log.Printf("[ERROR] failed to verify payload: %v", err)
 		return Response{StatusCode: 200}, nil
 	}
 	log.Printf("[INFO] Done slackRequestVerifier")
 
-	body := []byte(r.Body)
 	eventsAPIEvent, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
 	if err != nil {
 		log.Printf("[ERROR] Failed to parse slack event: %v", err)
@@ -60,18 +96,23 @@ func Handler(r events.APIGatewayProxyRequest) (Response, error) {
 		}, nil
 	}
 
-	log.Printf("[INFO] Start slackevents Handler")
-	switch event := eventsAPIEvent.InnerEvent.Data.(type) {
-	case *slackevents.ReactionAddedEvent:
-		log.Printf("[INFO] Start ReactionAddedEvent Handler")
-		err := reactionAddedEventHandler(event)
-		if err != nil {
-			log.Printf("[ERROR] Failed to handle ReactionAddedEvent: %v", err)
+	if retry, ok := archive.ParseRetry(request.Header(r.Headers)); ok {
+		log.Printf("[INFO] retried event: num=%d reason=%s", retry.Num, retry.Reason)
+		if retry.AlreadyAccepted() {
+			return Response{StatusCode: 200}, nil
 		}
-	default:
-		log.Printf("[INFO] unknow slackevents: %s", event)
 	}
-	log.Printf("[INFO] Done slackevents Handler")
+
+	if archiver.SkipEvent(eventsAPIEvent) {
+		log.Printf("[INFO] skip event: %s", eventsAPIEvent.InnerEvent.Type)
+	} else {
+		// Slack retries the event with "http_error" when the enqueue fails
+		if err := jobQueue.Enqueue(ctx, queue.Job{Kind: queue.KindSlackEvent, Payload: body}); err != nil {
+			log.Printf("[ERROR] Failed to enqueue slack event: %v", err)
+			return Response{StatusCode: http.StatusInternalServerError}, nil
+		}
+		log.Printf("[INFO] Done Enqueue")
+	}
 
 	response := map[string]string{"message": "OK"}
 	jsonResponse, err := json.Marshal(response)
@@ -89,232 +130,3 @@ func Handler(r events.APIGatewayProxyRequest) (Response, error) {
 		Headers:    headers,
 	}, nil
 }
-
-func slackRequestVerifier(r events.APIGatewayProxyRequest) error {
-	header := http.Header{}
-	for k, v := range r.Headers {
-		header.Set(k, v)
-	}
-
-	sv, err := slack.NewSecretsVerifier(header, os.Getenv("SLACK_SIGNING_SECRET"))
-	if err != nil {
-		return err
-	}
-
-	sv.Write([]byte(r.Body))
-	return sv.Ensure()
-}
-
-func reactionAddedEventHandler(event *slackevents.ReactionAddedEvent) error {
-	log.Printf("[INFO] event.Reaction: %s", event.Reaction)
-
-	if event.Reaction == "slack-to-notion" {
-		messages, err := getAllMessagesInThread(event)
-		if err != nil {
-			return err
-		}
-
-		if len(messages) != 0 {
-			log.Printf("[INFO] Start getMessagePermalink")
-
-			link, err := getMessagePermalink(event.Item.Channel, event.Item.Timestamp)
-			if err != nil {
-				return err
-			}
-
-			/*
-				要約はそこまで重要でいかつ無料枠を超えると課金が発生するので一旦なし
-				summarizedText, err := summarizeThreadByChatGPT(messages)
-				if err != nil {
-					return err
-				}
-			*/
-
-			log.Printf("[INFO] Start addPageToNotionDB")
-			if err := addPageToNotionDB(messages, link, ""); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
-/* get all messages in thread by slack.ReactionAddedEvent */
-func getAllMessagesInThread(event *slackevents.ReactionAddedEvent) ([]slack.Message, error) {
-	api := slack.New(os.Getenv("SLACK_TOKEN"))
-
-	var messages []slack.Message
-	var cursor string
-	for {
-		params := &slack.GetConversationRepliesParameters{
-			ChannelID: event.Item.Channel,
-			Timestamp: event.Item.Timestamp,
-			Limit:     1000,
-			Cursor:    cursor,
-		}
-		threadMessages, hasMore, nextCursor, err := api.GetConversationReplies(params)
-		if err != nil {
-			return messages, err
-		}
-
-		if threadMessages[0].Timestamp != threadMessages[0].ThreadTimestamp {
-			break
-		}
-
-		messages = append(messages, threadMessages...)
-
-		if !hasMore {
-			break
-		}
-		cursor = nextCursor
-	}
-	return messages, nil
-}
-
-func getMessagePermalink(channel string, timestamp string) (string, error) {
-	api := slack.New(os.Getenv("SLACK_TOKEN"))
-
-	permalink, err := api.GetPermalink(&slack.PermalinkParameters{
-		Channel: channel,
-		Ts:      timestamp,
-	})
-	if err != nil {
-		return "", err
-	}
-	return permalink, nil
-}
-
-func summarizeThreadByChatGPT(messages []slack.Message) (string, error) {
-	var sb strings.Builder
-	sb.WriteString("Write in Japanese\n 100文字にまとめてください. \n")
-
-	for i := range messages {
-		sb.WriteString(messages[i].Text)
-		sb.WriteString("\n\n")
-	}
-
-	client := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: "あなたは一流の編集者です。長い文章を要点を押さえた簡潔な文章にする力があります。",
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: sb.String(),
-				},
-			},
-		},
-	)
-	if err != nil {
-		return "", err
-	}
-
-	return resp.Choices[0].Message.Content, nil
-}
-
-func addPageToNotionDB(slackMessages []slack.Message, slackLink string, summarizedText string) error {
-	title := slackMessages[0]
-	notionTitle := []notion.RichText{
-		{
-			Type: notion.RichTextTypeText,
-			Text: &notion.Text{Content: title.Text},
-		},
-	}
-
-	children := []notion.Block{}
-	children = append(children, createSummarizedNotionCalloutBlock(summarizedText, slackLink))
-	children = append(children, ConvertSlackMessagesToNotionCalloutBlocks(slackMessages)...)
-
-	properties := &notion.DatabasePageProperties{"Name": notion.DatabasePageProperty{Title: notionTitle}}
-	params := notion.CreatePageParams{
-		ParentID:               os.Getenv("NOTION_DATABASE"),
-		ParentType:             notion.ParentTypeDatabase,
-		Title:                  notionTitle,
-		DatabasePageProperties: properties,
-		Children:               children,
-	}
-
-	notionClient := notion.NewClient(os.Getenv("NOTION_TOKEN"))
-	_, err := notionClient.CreatePage(context.Background(), params)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// createSummarizedNotionCalloutBlock creates a notion.Callout block that contains summarized text and slack link
-func createSummarizedNotionCalloutBlock(summarizedText string, slackLink string) notion.Block {
-	emoji := "📙"
-	return notion.Block{
-		Object: "block",
-		Type:   notion.BlockTypeCallout,
-		Callout: &notion.Callout{
-			RichTextBlock: notion.RichTextBlock{
-				Text: []notion.RichText{
-					{
-						Type: notion.RichTextTypeText,
-						Text: &notion.Text{Content: "■Slackのやり取り\n"},
-					},
-					{
-						Type: notion.RichTextTypeText,
-						Text: &notion.Text{
-							Content: slackLink,
-							Link:    &notion.Link{URL: slackLink},
-						},
-					},
-					{
-						Type: notion.RichTextTypeText,
-						Text: &notion.Text{Content: "\n\n■要約\n\n"},
-					},
-					{
-						Type: notion.RichTextTypeText,
-						Text: &notion.Text{Content: summarizedText},
-					},
-				},
-			},
-			Icon: &notion.Icon{
-				Type:  notion.IconTypeEmoji,
-				Emoji: &emoji,
-			},
-		},
-	}
-}
-
-// ConvertSlackMessagesToNotionCalloutBlocks convert slack messages to notion callout blocks
-func ConvertSlackMessagesToNotionCalloutBlocks(slackMessages []slack.Message) []notion.Block {
-	var children []notion.Block
-	for index, message := range slackMessages {
-		var emoji string
-		if index == 0 {
-			emoji = "❓"
-		} else {
-			emoji = "📝"
-		}
-
-		children = append(children, notion.Block{
-			Object: "block",
-			Type:   notion.BlockTypeCallout,
-			Callout: &notion.Callout{
-				RichTextBlock: notion.RichTextBlock{
-					Text: []notion.RichText{
-						{
-							Type: notion.RichTextTypeText,
-							Text: &notion.Text{Content: message.Text},
-						},
-					},
-				},
-				Icon: &notion.Icon{
-					Type:  notion.IconTypeEmoji,
-					Emoji: &emoji,
-				},
-			},
-		})
-	}
-	return children
-}
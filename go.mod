@@ -4,9 +4,14 @@ go 1.18
 
 require (
 	github.com/aws/aws-lambda-go v1.32.0
+	github.com/aws/aws-sdk-go v1.44.200
 	github.com/dstotijn/go-notion v0.6.1
 	github.com/sashabaranov/go-openai v1.5.0
 	github.com/slack-go/slack v0.10.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require github.com/gorilla/websocket v1.4.2 // indirect
+require (
+	github.com/gorilla/websocket v1.4.2 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+)
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/modal"
+	"github.com/slack-go/slack"
+)
+
+// interactionHandler handles an interaction and returns the response to Slack
+type interactionHandler func(ctx context.Context, callback slack.InteractionCallback) (Response, error)
+
+// handlerKey identifies the handler of an interaction by its type and callback_id
+type handlerKey struct {
+	Type       slack.InteractionType
+	CallbackID string
+}
+
+var handlers = map[handlerKey]interactionHandler{
+	{slack.InteractionTypeShortcut, modal.CallbackTaskShortcut}:      openTaskModal,
+	{slack.InteractionTypeMessageAction, modal.CallbackSaveShortcut}: openSaveModal,
+	{slack.InteractionTypeViewSubmission, modal.CallbackTask}:        submitTaskModal,
+	{slack.InteractionTypeViewSubmission, modal.CallbackSave}:        submitSaveModal,
+}
+
+// dispatch calls the handler registered for the interaction.
+// Interactions without a handler, such as block_actions of the modal inputs, are only acknowledged
+func dispatch(ctx context.Context, callback slack.InteractionCallback) (Response, error) {
+	key := handlerKey{Type: callback.Type, CallbackID: callbackID(callback)}
+	log.Printf("[INFO] interaction: type=%s callback_id=%s", key.Type, key.CallbackID)
+
+	handler, ok := handlers[key]
+	if !ok {
+		return Response{StatusCode: 200}, nil
+	}
+	return handler(ctx, callback)
+}
+
+// callbackID returns the callback_id of the view for view interactions and of the shortcut otherwise
+func callbackID(callback slack.InteractionCallback) string {
+	switch callback.Type {
+	case slack.InteractionTypeViewSubmission, slack.InteractionTypeViewClosed:
+		return callback.View.CallbackID
+	case slack.InteractionTypeBlockActions:
+		if callback.View.ID != "" {
+			return callback.View.CallbackID
+		}
+	}
+	return callback.CallbackID
+}
+
+// viewErrors keeps the modal open and shows the messages under the blocks keyed by block ID
+func viewErrors(errs map[string]string) (Response, error) {
+	return viewSubmissionResponse(slack.NewErrorsViewSubmissionResponse(errs))
+}
+
+// updateView replaces the submitted modal with view
+func updateView(view *slack.ModalViewRequest) (Response, error) {
+	return viewSubmissionResponse(slack.NewUpdateViewSubmissionResponse(view))
+}
+
+func viewSubmissionResponse(response *slack.ViewSubmissionResponse) (Response, error) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return Response{StatusCode: 200}, nil
+	}
+	return Response{
+		Body:       string(body),
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
+}
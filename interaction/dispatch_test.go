@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/modal"
+	"github.com/slack-go/slack"
+)
+
+func TestCallbackID(t *testing.T) {
+	view := slack.View{ID: "V1", CallbackID: modal.CallbackSave}
+
+	tests := []struct {
+		name     string
+		callback slack.InteractionCallback
+		want     string
+	}{
+		{
+			name:     "view_submission uses the view",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeViewSubmission, CallbackID: "ignored", View: view},
+			want:     modal.CallbackSave,
+		},
+		{
+			name:     "view_closed uses the view",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeViewClosed, View: view},
+			want:     modal.CallbackSave,
+		},
+		{
+			name:     "block_actions in a modal uses the view",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeBlockActions, View: view},
+			want:     modal.CallbackSave,
+		},
+		{
+			name:     "block_actions in a message uses the callback",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeBlockActions, CallbackID: "message_button"},
+			want:     "message_button",
+		},
+		{
+			name:     "shortcut",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeShortcut, CallbackID: modal.CallbackTaskShortcut},
+			want:     modal.CallbackTaskShortcut,
+		},
+		{
+			name:     "message_action",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeMessageAction, CallbackID: modal.CallbackSaveShortcut},
+			want:     modal.CallbackSaveShortcut,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := callbackID(tt.callback); got != tt.want {
+				t.Errorf("callbackID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	original := handlers
+	defer func() { handlers = original }()
+
+	// Every registered handler records its key instead of calling Slack and Notion
+	var called *handlerKey
+	handlers = map[handlerKey]interactionHandler{}
+	for key := range original {
+		key := key
+		handlers[key] = func(ctx context.Context, callback slack.InteractionCallback) (Response, error) {
+			called = &key
+			return Response{StatusCode: 200}, nil
+		}
+	}
+
+	tests := []struct {
+		name     string
+		callback slack.InteractionCallback
+		// want is the key of the called handler, or nil when the interaction is only acknowledged
+		want *handlerKey
+	}{
+		{
+			name:     "task shortcut",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeShortcut, CallbackID: modal.CallbackTaskShortcut},
+			want:     &handlerKey{slack.InteractionTypeShortcut, modal.CallbackTaskShortcut},
+		},
+		{
+			name:     "save message action",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeMessageAction, CallbackID: modal.CallbackSaveShortcut},
+			want:     &handlerKey{slack.InteractionTypeMessageAction, modal.CallbackSaveShortcut},
+		},
+		{
+			name:     "task submission",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeViewSubmission, View: slack.View{ID: "V1", CallbackID: modal.CallbackTask}},
+			want:     &handlerKey{slack.InteractionTypeViewSubmission, modal.CallbackTask},
+		},
+		{
+			name:     "save submission",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeViewSubmission, View: slack.View{ID: "V1", CallbackID: modal.CallbackSave}},
+			want:     &handlerKey{slack.InteractionTypeViewSubmission, modal.CallbackSave},
+		},
+		{
+			name:     "block_actions of a modal input",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeBlockActions, View: slack.View{ID: "V1", CallbackID: modal.CallbackTask}},
+		},
+		{
+			name:     "shortcut callback_id as a message action",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeMessageAction, CallbackID: modal.CallbackTaskShortcut},
+		},
+		{
+			name:     "unknown callback_id",
+			callback: slack.InteractionCallback{Type: slack.InteractionTypeShortcut, CallbackID: "unknown"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = nil
+			res, err := dispatch(context.Background(), tt.callback)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != 200 {
+				t.Errorf("StatusCode = %d, want 200", res.StatusCode)
+			}
+
+			switch {
+			case tt.want == nil && called != nil:
+				t.Errorf("called %+v, want only an acknowledgement", *called)
+			case tt.want == nil:
+				if res.Body != "" {
+					t.Errorf("Body = %q, want empty", res.Body)
+				}
+			case called == nil:
+				t.Errorf("called nothing, want %+v", *tt.want)
+			case *called != *tt.want:
+				t.Errorf("called %+v, want %+v", *called, *tt.want)
+			}
+		})
+	}
+}
+
+func TestViewErrors(t *testing.T) {
+	res, err := viewErrors(map[string]string{modal.TitleBlock: "タイトルを入力してください"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 {
+		t.Errorf("StatusCode = %d, want 200", res.StatusCode)
+	}
+	if got := res.Headers["Content-Type"]; got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	var body struct {
+		ResponseAction string            `json:"response_action"`
+		Errors         map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(res.Body), &body); err != nil {
+		t.Fatalf("Body = %q: %v", res.Body, err)
+	}
+	if body.ResponseAction != "errors" {
+		t.Errorf("response_action = %q, want errors", body.ResponseAction)
+	}
+	if len(body.Errors) != 1 || body.Errors[modal.TitleBlock] != "タイトルを入力してください" {
+		t.Errorf("errors = %v, want the title error", body.Errors)
+	}
+}
@@ -2,146 +2,102 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"log"
-	"net/http"
 	"net/url"
-	"os"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/dstotijn/go-notion"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/queue"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/request"
 	"github.com/slack-go/slack"
 )
 
-type Response events.APIGatewayProxyResponse
+// Response is the response struct for the lambda function
+type Response = events.APIGatewayProxyResponse
 
-func Handler(r events.APIGatewayProxyRequest) (Response, error) {
-	if err := slackRequestVerifier(r); err != nil {
-		log.Printf("[ERROR] failed to verify payload: %v", err)
-		return Response{StatusCode: 200}, nil
-	}
-	log.Printf("[INFO] Done slackRequestVerifier")
+var (
+	cfg      *config.Config
+	archiver *archive.Archiver
+	jobQueue queue.Queue
+)
 
-	body, err := decodeBody(r.Body)
-	if err != nil {
-		log.Printf("[ERROR] Failed to decode base64 encoded payload: %v", err)
-		return Response{StatusCode: 200}, nil
+// JobHandler generates the preview of the "Save to Notion" modal, saves the reviewed thread
+// and adds the submitted task
+func JobHandler(ctx context.Context, job queue.Job) error {
+	switch job.Kind {
+	case queue.KindSavePreview:
+		return previewJob(ctx, job.Payload)
+	case queue.KindSave:
+		return saveJob(ctx, job.Payload)
+	case queue.KindTask:
+		return taskJob(ctx, job.Payload)
+	default:
+		log.Printf("[INFO] unknow job: %s", job.Kind)
+		return nil
 	}
+}
 
-	/*
-		Slack sends a payload with a URL encoded body. We need to decode it first
-		https://api.slack.com/apis/connections/events-api#the-events-api__receiving-events
-	*/
-	parameters, err := url.QueryUnescape(string(body)[8:])
+// Handler verifies the request and dispatches the interaction payload to the handler of its type and callback_id
+func Handler(ctx context.Context, r events.APIGatewayProxyRequest) (Response, error) {
+	body, err := request.Body(r)
 	if err != nil {
-		log.Printf("[ERROR] Failed to decode unescape message from slack: %v", err)
+		log.Printf("[ERROR] Failed to decode base64 encoded payload: %v", err)
 		return Response{StatusCode: 200}, nil
 	}
 
-	var message slack.AttachmentActionCallback
-	if err := json.Unmarshal([]byte(parameters), &message); err != nil {
-		log.Printf("[ERROR] Failed to decode json message from slack: %v", err)
+	if err := request.Verify(request.Header(r.Headers), body, cfg.SlackSigningSecret); err != nil {
+		log.Printf("[ERROR] failed to verify payload: %v", err)
 		return Response{StatusCode: 200}, nil
 	}
+	log.Printf("[INFO] Done slackRequestVerifier")
 
-	title := message.View.State.Values["notion_title"]["title"].Value
-	content := message.View.State.Values["notion_content"]["content"].Value
-
-	if err := addPageToNotionDB(title, content); err != nil {
-		log.Printf("[ERROR] Failed to add page to Notion: %v", err)
+	callback, err := parseInteraction(body)
+	if err != nil {
+		log.Printf("[ERROR] Failed to parse interaction payload: %v", err)
 		return Response{StatusCode: 200}, nil
 	}
 
-	resp := Response{
-		StatusCode:      200,
-		IsBase64Encoded: false,
-		Headers: map[string]string{
-			"Content-Type":           "application/json",
-			"X-MyCompany-Func-Reply": "slack-interaction-handler",
-		},
-	}
-
-	return resp, nil
+	return dispatch(ctx, callback)
 }
 
-/*
-	SlackRequestVerifier is a function that verifies requests from Slack.
-	It validates the signature included in the Slack request to confirm that the request is from a legitimate source.
-	The function takes an events.APIGatewayProxyRequest object as input and returns an error if the request fails verification.
-*/
-func slackRequestVerifier(r events.APIGatewayProxyRequest) error {
-	header := http.Header{}
-	for k, v := range r.Headers {
-		header.Set(k, v)
-	}
-
-	sv, err := slack.NewSecretsVerifier(header, os.Getenv("SLACK_SIGNING_SECRET"))
+// parseInteraction decodes the JSON "payload" field of the application/x-www-form-urlencoded body.
+// https://api.slack.com/interactivity/handling#payloads
+func parseInteraction(body []byte) (slack.InteractionCallback, error) {
+	values, err := url.ParseQuery(string(body))
 	if err != nil {
-		return err
+		return slack.InteractionCallback{}, err
 	}
-
-	sv.Write([]byte(r.Body))
-	return sv.Ensure()
-}
-
-func decodeBody(body string) ([]byte, error) {
-	return base64.StdEncoding.DecodeString(body)
-}
-
-func makeHTTPHeader(headers map[string]string) http.Header {
-	result := make(http.Header)
-	for key, value := range headers {
-		result.Add(key, value)
-	}
-	return result
-}
-
-func addPageToNotionDB(title string, content string) error {
-	notionClient := notion.NewClient(os.Getenv("NOTION_TOKEN"))
-	ctx := context.Background()
-	notionTitle := []notion.RichText{
-		{
-			Type: notion.RichTextTypeText,
-			Text: &notion.Text{Content: title},
-		},
+	payload := values.Get("payload")
+	if payload == "" {
+		return slack.InteractionCallback{}, errors.New("payload is empty")
 	}
 
-	notionContext := []notion.RichText{
-		{
-			Type: notion.RichTextTypeText,
-			Text: &notion.Text{Content: content},
-		},
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(payload), &callback); err != nil {
+		return slack.InteractionCallback{}, err
 	}
+	return callback, nil
+}
 
-	children := []notion.Block{
-		{
-			Object:    "block",
-			Type:      notion.BlockTypeParagraph,
-			Paragraph: &notion.RichTextBlock{Text: notionContext},
-		},
-	}
-	properties := &notion.DatabasePageProperties{
-		"Name": notion.DatabasePageProperty{Title: notionTitle},
-	}
+func main() {
+	cfg = config.MustLoad(config.SlackToken, config.SlackSigningSecret, config.NotionToken, config.NotionDatabase)
 
-	params := notion.CreatePageParams{
-		ParentID:               os.Getenv("NOTION_DATABASE"),
-		ParentType:             notion.ParentTypeDatabase,
-		Title:                  notionTitle,
-		DatabasePageProperties: properties,
-		Children:               children,
+	a, err := archive.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("[ERROR] %v", err)
 	}
+	archiver = a
 
-	_, err := notionClient.CreatePage(ctx, params)
+	q, err := queue.NewLambdaInvoke(lambdacontext.FunctionName)
 	if err != nil {
-		return err
+		log.Fatalf("[ERROR] Failed to create job queue: %v", err)
 	}
+	jobQueue = q
 
-	return nil
-}
-
-func main() {
-	lambda.Start(Handler)
+	lambda.Start(queue.LambdaHandler(JobHandler, Handler))
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/modal"
+	"github.com/slack-go/slack"
+)
+
+// formBody returns the application/x-www-form-urlencoded body Slack posts with the payload
+func formBody(payload string) []byte {
+	return []byte(url.Values{"payload": {payload}}.Encode())
+}
+
+func TestParseInteraction(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           []byte
+		wantType       slack.InteractionType
+		wantCallbackID string
+		wantErr        bool
+	}{
+		{
+			name:           "view_submission",
+			body:           formBody(`{"type":"view_submission","view":{"id":"V1","callback_id":"notion_task"}}`),
+			wantType:       slack.InteractionTypeViewSubmission,
+			wantCallbackID: modal.CallbackTask,
+		},
+		{
+			name:           "block_actions in a modal",
+			body:           formBody(`{"type":"block_actions","view":{"id":"V1","callback_id":"notion_task"},"actions":[{"action_id":"due_date"}]}`),
+			wantType:       slack.InteractionTypeBlockActions,
+			wantCallbackID: modal.CallbackTask,
+		},
+		{
+			name:           "shortcut",
+			body:           formBody(`{"type":"shortcut","callback_id":"add_notion_task","trigger_id":"T1"}`),
+			wantType:       slack.InteractionTypeShortcut,
+			wantCallbackID: modal.CallbackTaskShortcut,
+		},
+		{
+			name:           "message_action",
+			body:           formBody(`{"type":"message_action","callback_id":"save_to_notion","channel":{"id":"C1"},"message":{"ts":"1.1"}}`),
+			wantType:       slack.InteractionTypeMessageAction,
+			wantCallbackID: modal.CallbackSaveShortcut,
+		},
+		{
+			name:    "missing payload",
+			body:    []byte("token=abc"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			body:    formBody(`{"type":`),
+			wantErr: true,
+		},
+		{
+			name:    "invalid form",
+			body:    []byte("payload=%zz"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callback, err := parseInteraction(tt.body)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("err = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if callback.Type != tt.wantType {
+				t.Errorf("Type = %q, want %q", callback.Type, tt.wantType)
+			}
+			if got := callbackID(callback); got != tt.wantCallbackID {
+				t.Errorf("callbackID = %q, want %q", got, tt.wantCallbackID)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/modal"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/queue"
+	"github.com/slack-go/slack"
+)
+
+// savePreview is the payload of the queue.KindSavePreview job
+type savePreview struct {
+	ViewID string `json:"view_id"`
+	modal.SaveMetadata
+}
+
+// openSaveModal opens the "Save to Notion" modal for the thread of the shortcut message.
+// The title and summary take longer than the 3 seconds Slack waits for, so the modal is
+// opened in a loading state and updated by the queue.KindSavePreview job
+func openSaveModal(ctx context.Context, message slack.InteractionCallback) (Response, error) {
+	threadTimestamp := message.Message.ThreadTimestamp
+	if threadTimestamp == "" {
+		threadTimestamp = message.Message.Timestamp
+	}
+	metadata := modal.SaveMetadata{
+		Channel:         message.Channel.ID,
+		ThreadTimestamp: threadTimestamp,
+		User:            message.User.ID,
+	}
+
+	slackClient := slack.New(cfg.SlackToken)
+	view, err := slackClient.OpenViewContext(ctx, message.TriggerID, *modal.NewSaveLoadingModal(metadata))
+	if err != nil {
+		log.Printf("[ERROR] failed to open modal: %v", err)
+		return Response{StatusCode: 200}, nil
+	}
+
+	payload, err := json.Marshal(savePreview{ViewID: view.ID, SaveMetadata: metadata})
+	if err != nil {
+		return Response{StatusCode: 200}, nil
+	}
+	if err := jobQueue.Enqueue(ctx, queue.Job{Kind: queue.KindSavePreview, Payload: payload}); err != nil {
+		log.Printf("[ERROR] Failed to enqueue save preview: %v", err)
+	}
+	return Response{StatusCode: 200}, nil
+}
+
+// previewJob fills the loading modal with the generated title and summary
+func previewJob(ctx context.Context, payload json.RawMessage) error {
+	var p savePreview
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+
+	slackClient := slack.New(cfg.SlackToken)
+	preview, err := archiver.Preview(ctx, p.Channel, p.ThreadTimestamp)
+	if err != nil {
+		log.Printf("[ERROR] Failed to preview thread: %v", err)
+		_, updateErr := slackClient.UpdateViewContext(ctx, *modal.NewSaveErrorModal(err.Error()), "", "", p.ViewID)
+		return updateErr
+	}
+
+	view := modal.NewSaveModal(cfg, p.SaveMetadata, preview.Route.Database, preview.Title, preview.Summary)
+	_, err = slackClient.UpdateViewContext(ctx, *view, "", "", p.ViewID)
+	return err
+}
+
+// submitSaveModal closes the modal and saves the thread with the reviewed values afterwards.
+// The modal is kept open with the error when the save cannot be enqueued
+func submitSaveModal(ctx context.Context, message slack.InteractionCallback) (Response, error) {
+	save, err := modal.ParseSave(message.View)
+	if err != nil {
+		log.Printf("[ERROR] Failed to parse save modal: %v", err)
+		return Response{StatusCode: 200}, nil
+	}
+	if errs := modal.ValidateSave(save, cfg.SaveDatabases(save.Channel)); errs != nil {
+		return viewErrors(errs)
+	}
+
+	payload, err := json.Marshal(archive.SaveRequest{
+		Channel:         save.Channel,
+		ThreadTimestamp: save.ThreadTimestamp,
+		User:            save.User,
+		Database:        save.Database,
+		Title:           save.Title,
+		Summary:         save.Summary,
+	})
+	if err != nil {
+		return Response{StatusCode: 200}, nil
+	}
+	if err := jobQueue.Enqueue(ctx, queue.Job{Kind: queue.KindSave, Payload: payload}); err != nil {
+		log.Printf("[ERROR] Failed to enqueue save: %v", err)
+		return viewErrors(map[string]string{modal.TitleBlock: "Notionへの保存に失敗しました。時間をおいて再度お試しください"})
+	}
+	return Response{StatusCode: 200}, nil
+}
+
+// saveJob archives the reviewed thread
+func saveJob(ctx context.Context, payload json.RawMessage) error {
+	var req archive.SaveRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return err
+	}
+	_, err := archiver.Save(ctx, req)
+	return err
+}
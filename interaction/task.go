@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/modal"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/queue"
+	"github.com/slack-go/slack"
+)
+
+// openTaskModal opens the task modal from the global shortcut, like the slash command does
+func openTaskModal(ctx context.Context, callback slack.InteractionCallback) (Response, error) {
+	slackClient := slack.New(cfg.SlackToken)
+	if _, err := slackClient.OpenViewContext(ctx, callback.TriggerID, *modal.NewTaskModal(cfg)); err != nil {
+		log.Printf("[ERROR] failed to open modal: %v", err)
+	}
+	return Response{StatusCode: 200}, nil
+}
+
+// taskRequest is the payload of the queue.KindTask job
+type taskRequest struct {
+	ViewID string `json:"view_id"`
+	modal.Task
+}
+
+// submitTaskModal validates the submitted task and enqueues it. Notion takes longer than the 3 seconds
+// Slack waits for, so the modal is replaced with a progress view that the queue.KindTask job updates.
+// Invalid answers are shown on the fields of the modal
+func submitTaskModal(ctx context.Context, callback slack.InteractionCallback) (Response, error) {
+	task := modal.ParseTask(callback.View.State)
+	if errs := modal.ValidateTask(task, cfg.ModalDatabases()); errs != nil {
+		return viewErrors(errs)
+	}
+
+	payload, err := json.Marshal(taskRequest{ViewID: callback.View.ID, Task: task})
+	if err != nil {
+		return Response{StatusCode: 200}, nil
+	}
+	if err := jobQueue.Enqueue(ctx, queue.Job{Kind: queue.KindTask, Payload: payload}); err != nil {
+		log.Printf("[ERROR] Failed to enqueue task: %v", err)
+		return viewErrors(map[string]string{modal.TitleBlock: "Notionへの追加に失敗しました。時間をおいて再度お試しください"})
+	}
+	return updateView(modal.NewTaskProgressModal())
+}
+
+// taskJob adds the task to the Notion database and shows the page URL, or the error, in the modal
+func taskJob(ctx context.Context, payload json.RawMessage) error {
+	var req taskRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return err
+	}
+
+	var page notion.Page
+	err := archiver.LoadModalSchemas(ctx, cfg.Modal, taskDatabases(cfg))
+	if err == nil {
+		task := archive.Task(req.Task)
+		task.Database = taskDatabase(cfg, req.Task)
+		page, err = archiver.AddTask(ctx, cfg.Modal, task)
+	}
+
+	var message string
+	switch {
+	case err == nil:
+		message = fmt.Sprintf("📝 Notionに追加しました: %s", page.URL)
+	case errors.Is(err, archive.ErrUnmappedAssignee):
+		message = "担当者が Notion に紐付いていません。担当者を外して再度お試しください"
+	case errors.Is(err, archive.ErrInvalidSchema):
+		message = "Notionデータベースの設定が正しくありません。管理者に連絡してください"
+	default:
+		message = "Notionへの追加に失敗しました。時間をおいて再度お試しください"
+	}
+
+	slackClient := slack.New(cfg.SlackToken)
+	if _, updateErr := slackClient.UpdateViewContext(ctx, *modal.NewTaskResultModal(message), "", "", req.ViewID); updateErr != nil {
+		log.Printf("[ERROR] Failed to update task modal: %v", updateErr)
+	}
+	return err
+}
+
+// taskDatabase returns the selected database when it is one of the choices, or the default database
+func taskDatabase(cfg *config.Config, task modal.Task) string {
+	for _, db := range cfg.ModalDatabases() {
+		if db.ID == task.Database {
+			return db.ID
+		}
+	}
+	return cfg.DefaultDatabase()
+}
+
+// taskDatabases returns every database taskDatabase can choose
+func taskDatabases(cfg *config.Config) []string {
+	databases := []string{cfg.DefaultDatabase()}
+	for _, db := range cfg.ModalDatabases() {
+		if db.ID != cfg.DefaultDatabase() {
+			databases = append(databases, db.ID)
+		}
+	}
+	return databases
+}
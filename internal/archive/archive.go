@@ -0,0 +1,193 @@
+// Package archive implements the "Slack thread → Notion page" pipeline shared by
+// the events Lambda and the local HTTP server.
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/mrkdwn"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/storage"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/summary"
+	"github.com/sashabaranov/go-openai"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// Options controls optional steps of the pipeline
+type Options struct {
+	// Routes decides which reactions archive a thread and to which Notion database
+	Routes []config.Route
+	// Summarizers are the named summarizers referenced by Route.Summary and ChannelSummaries
+	Summarizers map[string]summary.Summarizer
+	// ChannelSummaries overrides the summarizer of the route per channel ID
+	ChannelSummaries map[string]string
+	// ThreadIDProperty is the rich text property used to find the page of an archived thread.
+	// Defaults to DefaultThreadIDProperty
+	ThreadIDProperty string
+	// ReactionRemoved is applied to the archived page when the trigger reaction is removed
+	ReactionRemoved config.ReactionRemoved
+	// Notify is where the page URL, or the error, is posted after a thread is archived. Defaults to no post
+	Notify config.Notify
+	// NotionUsers maps Slack profile emails to Notion user IDs to render mentions as Notion person mentions
+	NotionUsers map[string]string
+	// Location is the timezone of the time shown on every archived message. Defaults to UTC
+	Location *time.Location
+	// Storage publishes files shared in threads. Files are linked to Slack when nil
+	Storage storage.Storage
+}
+
+// OptionsFromConfig returns the Options configured in cfg
+func OptionsFromConfig(cfg *config.Config) Options {
+	return Options{
+		Routes:           cfg.RouteTable(),
+		ThreadIDProperty: cfg.ThreadIDProperty,
+		ReactionRemoved:  cfg.ReactionRemoved,
+		Notify:           cfg.Notify,
+		NotionUsers:      cfg.NotionUsers,
+		Location:         cfg.Location(),
+		ChannelSummaries: cfg.ChannelSummaries,
+	}
+}
+
+// NewFromConfig returns an Archiver with the clients, file storage and summarizers configured in cfg.
+// The database schemas are loaded when a thread is first archived to them, so that cold starts
+// acknowledge Slack without waiting for Notion
+func NewFromConfig(cfg *config.Config) (*Archiver, error) {
+	store, err := storage.New(cfg.FileStorage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file storage: %w", err)
+	}
+	opts := OptionsFromConfig(cfg)
+	opts.Storage = store
+	if opts.Summarizers, err = summary.FromConfig(cfg); err != nil {
+		return nil, fmt.Errorf("failed to create summarizers: %w", err)
+	}
+
+	return New(
+		slack.New(cfg.SlackToken),
+		notion.NewClient(cfg.NotionToken),
+		openai.NewClient(cfg.OpenAIAPIKey),
+		opts,
+	), nil
+}
+
+// Archiver archives Slack threads to the Notion database with the injected clients
+type Archiver struct {
+	slack   SlackClient
+	notion  NotionClient
+	openai  OpenAIClient
+	options Options
+	locks   threadLocks
+
+	directory *Directory
+	parser    *mrkdwn.Parser
+	schemas   schemas
+}
+
+// New returns an Archiver that uses the given clients
+func New(slackClient SlackClient, notionClient NotionClient, openaiClient OpenAIClient, opts Options) *Archiver {
+	directory := NewDirectory(slackClient, opts.NotionUsers)
+	return &Archiver{
+		slack:     slackClient,
+		notion:    notionClient,
+		openai:    openaiClient,
+		options:   opts,
+		directory: directory,
+		parser:    &mrkdwn.Parser{Mentions: directory},
+	}
+}
+
+// Storage returns Options.Storage
+func (a *Archiver) Storage() storage.Storage {
+	return a.options.Storage
+}
+
+// ReactionAddedEventHandler archives the reacted thread to the Notion database of the matched route
+func (a *Archiver) ReactionAddedEventHandler(ctx context.Context, event *slackevents.ReactionAddedEvent) error {
+	log.Printf("[INFO] event.Reaction: %s", event.Reaction)
+
+	route, ok := a.FindRoute(event.Reaction, event.Item.Channel)
+	if !ok {
+		return nil
+	}
+	log.Printf("[INFO] route: reaction=%s database=%s", route.Reaction, route.Database)
+
+	if err := a.loadSchema(ctx, route); err != nil {
+		a.Notify(ctx, event.Item.Channel, event.Item.Timestamp, event.User, notion.Page{}, err)
+		return err
+	}
+
+	thread, ok, err := a.fetchThread(event.Item.Channel, event.Item.Timestamp, event.User, false)
+	if err != nil {
+		a.Notify(ctx, event.Item.Channel, event.Item.Timestamp, event.User, notion.Page{}, err)
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	// A thread archived again only refreshes the properties, without copying files and summarizing again
+	page, found, err := a.UpdateArchivedPage(ctx, route, thread, "")
+	if err == nil && !found {
+		thread.FileURLs = a.CopyFiles(ctx, thread.Messages)
+		digest := a.Digest(ctx, route, thread)
+
+		log.Printf("[INFO] Start AddPageToNotionDB")
+		page, err = a.AddPageToNotionDB(ctx, route, thread, digest)
+	}
+	a.Notify(ctx, thread.Channel, thread.Timestamp, thread.Reactor, page, err)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] archived thread %s to page %s", thread.Key(), page.ID)
+	return nil
+}
+
+// fetchThread gets the messages and permalink of the thread. Files are not copied yet.
+// It returns false when ts is not the parent message of a thread, unless standalone is set
+// and ts is a message without replies, which is fetched as a thread of one message
+func (a *Archiver) fetchThread(channel string, ts string, reactor string, standalone bool) (Thread, bool, error) {
+	messages, err := a.GetThreadMessages(channel, ts)
+	if err == nil && len(messages) == 0 && standalone {
+		messages, err = a.GetMessage(channel, ts)
+	}
+	if err != nil {
+		return Thread{}, false, err
+	}
+
+	if len(messages) == 0 {
+		return Thread{}, false, nil
+	}
+
+	log.Printf("[INFO] Start GetMessagePermalink")
+	link, err := a.GetMessagePermalink(channel, ts)
+	if err != nil {
+		return Thread{}, false, err
+	}
+
+	return Thread{
+		Channel:   channel,
+		Timestamp: ts,
+		Permalink: link,
+		Messages:  messages,
+		Reactor:   reactor,
+	}, true, nil
+}
+
+// FindRoute returns the first route that matches the reaction in the channel.
+// Routes are evaluated in order, so channel scoped routes should be listed before
+// the catch-all route of the same reaction.
+func (a *Archiver) FindRoute(reaction string, channel string) (config.Route, bool) {
+	for _, r := range a.options.Routes {
+		if r.Matches(reaction, channel) {
+			return r, true
+		}
+	}
+	return config.Route{}, false
+}
@@ -0,0 +1,599 @@
+package archive_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive/archivetest"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/summary"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+const (
+	channel  = "C1"
+	parentTS = "1680000000.000100"
+	database = "db-1"
+	reaction = "slack-to-notion"
+)
+
+var routes = []config.Route{{Reaction: reaction, Database: database}}
+
+// newThread returns a thread of n messages whose parent is parentTS
+func newThread(n int) []slack.Message {
+	messages := make([]slack.Message, n)
+	for i := range messages {
+		messages[i] = slack.Message{Msg: slack.Msg{
+			User:            fmt.Sprintf("U%d", i%2+1),
+			Text:            fmt.Sprintf("message %d", i),
+			Timestamp:       fmt.Sprintf("1680000000.%06d", 100+i),
+			ThreadTimestamp: parentTS,
+		}}
+	}
+	return messages
+}
+
+func reactionAdded(name string) *slackevents.ReactionAddedEvent {
+	return &slackevents.ReactionAddedEvent{
+		User:     "U9",
+		Reaction: name,
+		Item:     slackevents.Item{Channel: channel, Timestamp: parentTS},
+	}
+}
+
+func reactionRemoved(name string) *slackevents.ReactionRemovedEvent {
+	return &slackevents.ReactionRemovedEvent{
+		User:     "U9",
+		Reaction: name,
+		Item:     slackevents.Item{Channel: channel, Timestamp: parentTS},
+	}
+}
+
+// plainText concatenates the text of the rich text elements of a block
+func plainText(b notion.Block) string {
+	var text []notion.RichText
+	switch b.Type {
+	case notion.BlockTypeCallout:
+		text = b.Callout.Text
+	case notion.BlockTypeParagraph:
+		text = b.Paragraph.Text
+	}
+	var sb strings.Builder
+	for _, rt := range text {
+		if rt.Text != nil {
+			sb.WriteString(rt.Text.Content)
+		}
+	}
+	return sb.String()
+}
+
+// newNotion returns a Notion fake with the schema of database
+func newNotion() *archivetest.Notion {
+	return &archivetest.Notion{Databases: map[string]notion.Database{
+		database: {ID: database, Properties: notion.DatabaseProperties{
+			"Name":                          {Type: notion.DBPropTypeTitle},
+			archive.DefaultThreadIDProperty: {Type: notion.DBPropTypeRichText},
+			"Status":                        {Type: notion.DBPropTypeSelect},
+		}},
+	}}
+}
+
+func onlyPage(t *testing.T, n *archivetest.Notion) *archivetest.Page {
+	t.Helper()
+	if len(n.Pages) != 1 {
+		t.Fatalf("pages = %d, want 1", len(n.Pages))
+	}
+	for _, p := range n.Pages {
+		return p
+	}
+	return nil
+}
+
+func TestReactionAddedEventHandler(t *testing.T) {
+	slackErr := errors.New("ratelimited")
+
+	tests := []struct {
+		name     string
+		reaction string
+		messages int
+		pageSize int
+		slackErr error
+		// wantMessages is the number of message callouts following the summary, -1 for no page
+		wantMessages int
+		wantErr      error
+	}{
+		{name: "non-trigger reaction", reaction: "thumbsup", messages: 3, wantMessages: -1},
+		{name: "trigger reaction", reaction: reaction, messages: 3, wantMessages: 3},
+		{name: "trigger reaction with colons", reaction: ":" + reaction + ":", messages: 1, wantMessages: 1},
+		{name: "paginated replies", reaction: reaction, messages: 5, pageSize: 2, wantMessages: 5},
+		{name: "slack error", reaction: reaction, messages: 3, slackErr: slackErr, wantMessages: -1, wantErr: slackErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &archivetest.Slack{PageSize: tt.pageSize}
+			s.AddThread(channel, newThread(tt.messages)...)
+			s.Err = tt.slackErr
+			n := newNotion()
+			a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{Routes: routes})
+
+			err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(tt.reaction))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantMessages < 0 {
+				if len(n.Created) != 0 {
+					t.Fatalf("created %d pages, want none", len(n.Created))
+				}
+				return
+			}
+			if len(n.Created) != 1 {
+				t.Fatalf("created %d pages, want 1", len(n.Created))
+			}
+			created := n.Created[0]
+			if created.ParentID != database {
+				t.Errorf("parent = %s, want %s", created.ParentID, database)
+			}
+			if got := (*created.DatabasePageProperties)[archive.DefaultThreadIDProperty].RichText[0].Text.Content; got != channel+":"+parentTS {
+				t.Errorf("thread ID = %s, want %s", got, channel+":"+parentTS)
+			}
+			if got := created.Title[0].Text.Content; got != "message 0" {
+				t.Errorf("title = %q, want %q", got, "message 0")
+			}
+
+			children := created.Children
+			if len(children) != tt.wantMessages+1 {
+				t.Fatalf("children = %d, want %d", len(children), tt.wantMessages+1)
+			}
+			if got := plainText(children[0]); !strings.Contains(got, "https://example.slack.com/archives/C1/p"+parentTS) {
+				t.Errorf("summary callout = %q, want the thread permalink", got)
+			}
+			for i, c := range children[1:] {
+				if c.Type != notion.BlockTypeCallout {
+					t.Fatalf("children[%d] is %s, want callout", i+1, c.Type)
+				}
+				if want := fmt.Sprintf("message %d", i); !strings.Contains(plainText(c), want) {
+					t.Errorf("children[%d] = %q, want %q", i+1, plainText(c), want)
+				}
+			}
+		})
+	}
+}
+
+// countingSummarizer counts the summarized threads
+type countingSummarizer struct {
+	calls int
+}
+
+func (s *countingSummarizer) Summarize(ctx context.Context, thread summary.Thread, prompt config.Prompt) (string, error) {
+	s.calls++
+	return "summary", nil
+}
+
+// updateCountingNotion counts the page updates
+type updateCountingNotion struct {
+	*archivetest.Notion
+	updates int
+}
+
+func (n *updateCountingNotion) UpdatePage(ctx context.Context, pageID string, params notion.UpdatePageParams) (notion.Page, error) {
+	n.updates++
+	return n.Notion.UpdatePage(ctx, pageID, params)
+}
+
+func TestReactionAddedEventHandlerDeduplicates(t *testing.T) {
+	s := &archivetest.Slack{}
+	s.AddThread(channel, newThread(2)...)
+	n := newNotion()
+	counting := &updateCountingNotion{Notion: n}
+	summarizer := &countingSummarizer{}
+	a := archive.New(s, counting, &archivetest.OpenAI{}, archive.Options{
+		Routes:      []config.Route{{Reaction: reaction, Database: database, Summary: "counting"}},
+		Summarizers: map[string]summary.Summarizer{"counting": summarizer},
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(n.Created) != 1 {
+		t.Errorf("created %d pages, want 1", len(n.Created))
+	}
+	if summarizer.calls != 1 {
+		t.Errorf("summarized %d times, want only for the new page", summarizer.calls)
+	}
+	if counting.updates != 0 {
+		t.Errorf("updated the page %d times, want none without properties to refresh", counting.updates)
+	}
+	page := onlyPage(t, n)
+	if got := len(page.Children); got != 3 {
+		t.Errorf("children = %d, want the body left as it is", got)
+	}
+}
+
+// staleNotion misses every page in the first query of each thread, like a query that races
+// with the page creation of another invocation
+type staleNotion struct {
+	*archivetest.Notion
+	queried map[string]bool
+}
+
+func (n *staleNotion) QueryDatabase(ctx context.Context, id string, query *notion.DatabaseQuery) (notion.DatabaseQueryResponse, error) {
+	key := query.Filter.Text.Equals
+	if !n.queried[key] {
+		n.queried[key] = true
+		return notion.DatabaseQueryResponse{}, nil
+	}
+	return n.Notion.QueryDatabase(ctx, id, query)
+}
+
+func TestAddPageToNotionDBResolvesConcurrentDuplicates(t *testing.T) {
+	s := &archivetest.Slack{}
+	s.AddThread(channel, newThread(2)...)
+	n := newNotion()
+
+	// Each archiver stands for another Lambda invocation that does not share the in-process lock
+	var pages []notion.Page
+	for i := 0; i < 2; i++ {
+		a := archive.New(s, &staleNotion{Notion: n, queried: map[string]bool{}}, &archivetest.OpenAI{}, archive.Options{Routes: routes})
+		page, err := a.AddPageToNotionDB(context.Background(), routes[0], archive.Thread{
+			Channel:   channel,
+			Timestamp: parentTS,
+			Messages:  newThread(2),
+		}, archive.Digest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+	}
+
+	if len(n.Created) != 2 {
+		t.Fatalf("created %d pages, want 2", len(n.Created))
+	}
+	if pages[0].ID != "page-1" || pages[1].ID != "page-1" {
+		t.Errorf("returned pages %s and %s, want page-1 for both", pages[0].ID, pages[1].ID)
+	}
+	if n.Pages["page-1"].Archived || !n.Pages["page-2"].Archived {
+		t.Errorf("archived page-1=%v page-2=%v, want only the later duplicate archived", n.Pages["page-1"].Archived, n.Pages["page-2"].Archived)
+	}
+}
+
+func TestReactionAddedEventHandlerChunksBlocks(t *testing.T) {
+	messages := newThread(150)
+	messages[1].Text = strings.Repeat("あ", 4500)
+
+	s := &archivetest.Slack{}
+	s.AddThread(channel, messages...)
+	n := newNotion()
+	a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{Routes: routes})
+
+	if err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(n.Created[0].Children); got != 100 {
+		t.Errorf("children of CreatePage = %d, want 100", got)
+	}
+	page := onlyPage(t, n)
+	if got := len(page.Children); got != 151 {
+		t.Errorf("children of page = %d, want 151", got)
+	}
+	long := page.Children[2].Callout.Text
+	var total int
+	for _, rt := range long {
+		if l := len([]rune(rt.Text.Content)); l > 2000 {
+			t.Errorf("rich text has %d characters, want at most 2000", l)
+		}
+		total += len([]rune(rt.Text.Content))
+	}
+	if total < 4500 {
+		t.Errorf("long message has %d characters, want the whole text", total)
+	}
+}
+
+// appendFailingNotion fails to append blocks to pages
+type appendFailingNotion struct {
+	*archivetest.Notion
+	err error
+}
+
+func (n *appendFailingNotion) AppendBlockChildren(ctx context.Context, blockID string, children []notion.Block) (notion.BlockChildrenResponse, error) {
+	return notion.BlockChildrenResponse{}, n.err
+}
+
+func TestReactionAddedEventHandlerArchivesPartialPage(t *testing.T) {
+	appendErr := errors.New("conflict_error")
+
+	s := &archivetest.Slack{}
+	s.AddThread(channel, newThread(150)...)
+	n := newNotion()
+	a := archive.New(s, &appendFailingNotion{Notion: n, err: appendErr}, &archivetest.OpenAI{}, archive.Options{Routes: routes})
+
+	if err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction)); !errors.Is(err, appendErr) {
+		t.Fatalf("err = %v, want %v", err, appendErr)
+	}
+	if !onlyPage(t, n).Archived {
+		t.Errorf("partial page is not archived")
+	}
+
+	// Archiving the thread again creates a new page instead of refreshing the partial one
+	a = archive.New(s, n, &archivetest.OpenAI{}, archive.Options{Routes: routes})
+	if err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction)); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Created) != 2 {
+		t.Errorf("created %d pages, want 2", len(n.Created))
+	}
+	if got := len(n.Pages["page-2"].Children); got != 151 {
+		t.Errorf("children of page-2 = %d, want 151", got)
+	}
+}
+
+func TestReactionAddedEventHandlerNotifies(t *testing.T) {
+	notionErr := errors.New("validation_error")
+
+	tests := []struct {
+		name       string
+		mode       string
+		notionErr  error
+		properties config.PropertyMapping
+		wantErr    bool
+		want       []archivetest.Post
+	}{
+		{
+			name: "thread",
+			mode: config.NotifyThread,
+			want: []archivetest.Post{{Channel: channel, Text: "📝 Notionに保存しました: https://www.notion.so/page-1", ThreadTS: parentTS}},
+		},
+		{
+			name: "ephemeral",
+			mode: config.NotifyEphemeral,
+			want: []archivetest.Post{{Channel: channel, User: "U9", Text: "📝 Notionに保存しました: https://www.notion.so/page-1", ThreadTS: parentTS}},
+		},
+		{
+			name: "none",
+			mode: config.NotifyNone,
+		},
+		{
+			name:      "error",
+			mode:      config.NotifyThread,
+			notionErr: notionErr,
+			wantErr:   true,
+			want:      []archivetest.Post{{Channel: channel, Text: "⚠️ Notionへの保存に失敗しました: failed to find database db-1: validation_error", ThreadTS: parentTS}},
+		},
+		{
+			name:       "invalid schema",
+			mode:       config.NotifyThread,
+			properties: config.PropertyMapping{"Missing": config.SourceChannel},
+			wantErr:    true,
+			want:       []archivetest.Post{{Channel: channel, Text: `⚠️ Notionへの保存に失敗しました: invalid schema of database db-1: property "Missing" does not exist`, ThreadTS: parentTS}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &archivetest.Slack{}
+			s.AddThread(channel, newThread(1)...)
+			n := newNotion()
+			n.Err = tt.notionErr
+			a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{
+				Routes: []config.Route{{Reaction: reaction, Database: database, Properties: tt.properties}},
+				Notify: config.Notify{Mode: tt.mode},
+			})
+
+			err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction))
+			if (err != nil) != tt.wantErr || (tt.notionErr != nil && !errors.Is(err, tt.notionErr)) {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(s.Posts) != len(tt.want) {
+				t.Fatalf("posts = %+v, want %+v", s.Posts, tt.want)
+			}
+			for i := range tt.want {
+				if s.Posts[i] != tt.want[i] {
+					t.Errorf("posts[%d] = %+v, want %+v", i, s.Posts[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMessageEventHandler(t *testing.T) {
+	reply := func(mutate func(e *slackevents.MessageEvent)) *slackevents.MessageEvent {
+		e := &slackevents.MessageEvent{
+			Channel:         channel,
+			User:            "U2",
+			Text:            "new reply",
+			TimeStamp:       "1680000001.000100",
+			ThreadTimeStamp: parentTS,
+		}
+		if mutate != nil {
+			mutate(e)
+		}
+		return e
+	}
+
+	tests := []struct {
+		name     string
+		archived bool
+		event    *slackevents.MessageEvent
+		wantNew  int
+	}{
+		{name: "reply", archived: true, event: reply(nil), wantNew: 1},
+		{name: "broadcast reply", archived: true, event: reply(func(e *slackevents.MessageEvent) { e.SubType = "thread_broadcast" }), wantNew: 1},
+		{name: "not archived", event: reply(nil)},
+		{name: "top-level message", archived: true, event: reply(func(e *slackevents.MessageEvent) { e.ThreadTimeStamp = "" })},
+		{name: "parent message", archived: true, event: reply(func(e *slackevents.MessageEvent) { e.TimeStamp = parentTS })},
+		{name: "edit", archived: true, event: reply(func(e *slackevents.MessageEvent) { e.SubType = "message_changed" })},
+		{name: "bot reply", archived: true, event: reply(func(e *slackevents.MessageEvent) { e.BotID = "B1" })},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &archivetest.Slack{}
+			s.AddThread(channel, newThread(2)...)
+			n := newNotion()
+			a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{Routes: routes})
+
+			if tt.archived {
+				if err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			before := 0
+			if tt.archived {
+				before = len(onlyPage(t, n).Children)
+			}
+
+			if err := a.MessageEventHandler(context.Background(), tt.event); err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.archived {
+				if len(n.Pages) != 0 {
+					t.Fatalf("pages = %d, want none", len(n.Pages))
+				}
+				return
+			}
+			page := onlyPage(t, n)
+			if got := len(page.Children) - before; got != tt.wantNew {
+				t.Fatalf("appended %d blocks, want %d", got, tt.wantNew)
+			}
+			if tt.wantNew != 0 {
+				if got := plainText(page.Children[len(page.Children)-1]); !strings.Contains(got, "new reply") {
+					t.Errorf("appended %q, want the reply", got)
+				}
+			}
+		})
+	}
+}
+
+func TestReactionRemovedEventHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		// action is the configured config.ReactionRemoved.Action
+		action string
+		// remaining is the count of the trigger reaction left on the message
+		remaining    int
+		wantArchived bool
+		wantStatus   string
+	}{
+		{name: "none", action: config.ReactionRemovedNone},
+		{name: "archive", action: config.ReactionRemovedArchive, wantArchived: true},
+		{name: "status", action: config.ReactionRemovedStatus, wantStatus: "Withdrawn"},
+		{name: "reaction still present", action: config.ReactionRemovedArchive, remaining: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := newThread(2)
+			if tt.remaining > 0 {
+				messages[0].Reactions = []slack.ItemReaction{{Name: reaction, Count: tt.remaining}}
+			}
+			s := &archivetest.Slack{}
+			s.AddThread(channel, messages...)
+			n := newNotion()
+			a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{
+				Routes: routes,
+				ReactionRemoved: config.ReactionRemoved{
+					Action:         tt.action,
+					StatusProperty: "Status",
+					StatusValue:    "Withdrawn",
+				},
+			})
+
+			if err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction)); err != nil {
+				t.Fatal(err)
+			}
+			if err := a.ReactionRemovedEventHandler(context.Background(), reactionRemoved(reaction)); err != nil {
+				t.Fatal(err)
+			}
+
+			page := onlyPage(t, n)
+			if page.Archived != tt.wantArchived {
+				t.Errorf("archived = %v, want %v", page.Archived, tt.wantArchived)
+			}
+			if got := status(page); got != tt.wantStatus {
+				t.Errorf("status = %q, want %q", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestReactionRemovedEventHandlerValidatesStatusProperty(t *testing.T) {
+	tests := []struct {
+		name   string
+		status *notion.DatabaseProperty
+	}{
+		{name: "missing"},
+		{name: "not a select", status: &notion.DatabaseProperty{Type: notion.DBPropTypeMultiSelect}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newNotion()
+			delete(n.Databases[database].Properties, "Status")
+			if tt.status != nil {
+				n.Databases[database].Properties["Status"] = *tt.status
+			}
+			a := archive.New(&archivetest.Slack{}, n, &archivetest.OpenAI{}, archive.Options{
+				Routes:          routes,
+				ReactionRemoved: config.ReactionRemoved{Action: config.ReactionRemovedStatus, StatusProperty: "Status", StatusValue: "Withdrawn"},
+			})
+
+			err := a.ReactionRemovedEventHandler(context.Background(), reactionRemoved(reaction))
+			if !errors.Is(err, archive.ErrInvalidSchema) || !strings.Contains(err.Error(), `"Status" must be a select property`) {
+				t.Errorf("err = %v, want %v of the status property", err, archive.ErrInvalidSchema)
+			}
+		})
+	}
+}
+
+func TestReactionAddedEventHandlerRestoresStatus(t *testing.T) {
+	s := &archivetest.Slack{}
+	s.AddThread(channel, newThread(2)...)
+	n := newNotion()
+	a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{
+		Routes: routes,
+		ReactionRemoved: config.ReactionRemoved{
+			Action:         config.ReactionRemovedStatus,
+			StatusProperty: "Status",
+			StatusValue:    "Withdrawn",
+			RestoreValue:   "Archived",
+		},
+	})
+
+	steps := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{name: "archive", run: func() error { return a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction)) }},
+		{name: "withdraw", run: func() error { return a.ReactionRemovedEventHandler(context.Background(), reactionRemoved(reaction)) }, want: "Withdrawn"},
+		{name: "archive again", run: func() error { return a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction)) }, want: "Archived"},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := status(onlyPage(t, n)); got != step.want {
+			t.Errorf("%s: status = %q, want %q", step.name, got, step.want)
+		}
+	}
+}
+
+func status(page *archivetest.Page) string {
+	if sel := page.Properties["Status"].Select; sel != nil {
+		return sel.Name
+	}
+	return ""
+}
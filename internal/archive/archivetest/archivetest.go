@@ -0,0 +1,460 @@
+// Package archivetest provides in-memory implementations of the archive clients
+// so the pipeline can be exercised without Slack, Notion or OpenAI.
+package archivetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/storage"
+	"github.com/sashabaranov/go-openai"
+	"github.com/slack-go/slack"
+)
+
+var (
+	// ErrThreadNotFound is returned by Slack when no thread is stored for the requested timestamp
+	ErrThreadNotFound = errors.New("thread_not_found")
+	// ErrMessageNotFound is returned by Slack when no message is stored for the requested timestamp
+	ErrMessageNotFound = errors.New("message_not_found")
+	// ErrUserNotFound is returned by Slack for unknown users
+	ErrUserNotFound = errors.New("user_not_found")
+	// ErrChannelNotFound is returned by Slack for unknown channels
+	ErrChannelNotFound = errors.New("channel_not_found")
+	// ErrFileNotFound is returned by Slack for unknown download URLs
+	ErrFileNotFound = errors.New("file_not_found")
+	// ErrObjectNotFound is returned by Notion when the page or block does not exist
+	ErrObjectNotFound = errors.New("object_not_found")
+)
+
+var (
+	_ archive.SlackClient  = (*Slack)(nil)
+	_ archive.NotionClient = (*Notion)(nil)
+	_ archive.OpenAIClient = (*OpenAI)(nil)
+	_ storage.Storage      = (*Storage)(nil)
+)
+
+// Slack is an in-memory archive.SlackClient
+type Slack struct {
+	mu sync.Mutex
+
+	// Threads holds the messages of each thread keyed by channel ID and thread timestamp
+	Threads map[string]map[string][]slack.Message
+	// Users holds the users keyed by user ID
+	Users map[string]*slack.User
+	// Channels holds the channels keyed by channel ID
+	Channels map[string]*slack.Channel
+	// UserGroups holds every usergroup of the workspace
+	UserGroups []slack.UserGroup
+	// Files holds the content of the files keyed by download URL
+	Files map[string][]byte
+	// Posts holds every posted message and ephemeral message in order
+	Posts []Post
+	// PageSize is the number of messages returned per GetConversationReplies call. 0 returns all messages at once
+	PageSize int
+	// Err is returned by every call when set
+	Err error
+}
+
+// AddThread stores messages as a thread of the channel
+func (s *Slack) AddThread(channel string, messages ...slack.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Threads == nil {
+		s.Threads = map[string]map[string][]slack.Message{}
+	}
+	if s.Threads[channel] == nil {
+		s.Threads[channel] = map[string][]slack.Message{}
+	}
+	if len(messages) == 0 {
+		return
+	}
+	s.Threads[channel][messages[0].Timestamp] = messages
+}
+
+// GetConversationReplies returns the stored thread page by page
+func (s *Slack) GetConversationReplies(params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return nil, false, "", s.Err
+	}
+
+	messages, ok := s.Threads[params.ChannelID][params.Timestamp]
+	if !ok {
+		return nil, false, "", ErrThreadNotFound
+	}
+
+	start := 0
+	if params.Cursor != "" {
+		for i := range messages {
+			if messages[i].Timestamp == params.Cursor {
+				start = i
+				break
+			}
+		}
+	}
+
+	end := len(messages)
+	if s.PageSize > 0 && start+s.PageSize < end {
+		end = start + s.PageSize
+	}
+
+	// Slack returns the parent message at the head of every page
+	page := messages[start:end]
+	if start > 0 {
+		page = append([]slack.Message{messages[0]}, page...)
+	}
+
+	if end < len(messages) {
+		return page, true, messages[end].Timestamp, nil
+	}
+	return page, false, "", nil
+}
+
+// GetPermalink returns a fake permalink of the message
+func (s *Slack) GetPermalink(params *slack.PermalinkParameters) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return "", s.Err
+	}
+	return "https://example.slack.com/archives/" + params.Channel + "/p" + params.Ts, nil
+}
+
+// GetReactions returns the reactions of the stored message
+func (s *Slack) GetReactions(item slack.ItemRef, params slack.GetReactionsParameters) ([]slack.ItemReaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	for _, messages := range s.Threads[item.Channel] {
+		for _, m := range messages {
+			if m.Timestamp == item.Timestamp {
+				return m.Reactions, nil
+			}
+		}
+	}
+	return nil, ErrMessageNotFound
+}
+
+// GetUserInfo returns the stored user
+func (s *Slack) GetUserInfo(user string) (*slack.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if u, ok := s.Users[user]; ok {
+		return u, nil
+	}
+	return nil, ErrUserNotFound
+}
+
+// GetConversationInfo returns the stored channel
+func (s *Slack) GetConversationInfo(channelID string, includeLocale bool) (*slack.Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if c, ok := s.Channels[channelID]; ok {
+		return c, nil
+	}
+	return nil, ErrChannelNotFound
+}
+
+// GetUserGroups returns every stored usergroup
+func (s *Slack) GetUserGroups(options ...slack.GetUserGroupsOption) ([]slack.UserGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return s.UserGroups, nil
+}
+
+// GetFileContext writes the stored file content
+func (s *Slack) GetFileContext(ctx context.Context, downloadURL string, writer io.Writer) error {
+	s.mu.Lock()
+	content, ok := s.Files[downloadURL]
+	err := s.Err
+	s.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrFileNotFound
+	}
+	_, err = writer.Write(content)
+	return err
+}
+
+// Post is a message posted through Slack
+type Post struct {
+	Channel string
+	// User is the recipient of an ephemeral message, empty for messages visible to everyone
+	User     string
+	Text     string
+	ThreadTS string
+}
+
+// PostMessageContext records the message
+func (s *Slack) PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error) {
+	ts, err := s.post(channelID, "", options)
+	return channelID, ts, err
+}
+
+// PostEphemeralContext records the ephemeral message
+func (s *Slack) PostEphemeralContext(ctx context.Context, channelID string, userID string, options ...slack.MsgOption) (string, error) {
+	return s.post(channelID, userID, options)
+}
+
+func (s *Slack) post(channelID string, userID string, options []slack.MsgOption) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return "", s.Err
+	}
+	_, values, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	if err != nil {
+		return "", err
+	}
+	s.Posts = append(s.Posts, Post{
+		Channel:  channelID,
+		User:     userID,
+		Text:     values.Get("text"),
+		ThreadTS: values.Get("thread_ts"),
+	})
+	return fmt.Sprintf("%d.000000", len(s.Posts)), nil
+}
+
+// Storage is an in-memory storage.Storage
+type Storage struct {
+	mu sync.Mutex
+
+	// Objects holds the stored content keyed by key
+	Objects map[string][]byte
+	// Err is returned by every call when set
+	Err error
+}
+
+// Put stores the content and returns a fake public URL
+func (s *Storage) Put(ctx context.Context, key string, contentType string, body io.Reader) (string, error) {
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return "", s.Err
+	}
+	if s.Objects == nil {
+		s.Objects = map[string][]byte{}
+	}
+	s.Objects[key] = content
+	return storage.PublicURL("https://files.example.com", key), nil
+}
+
+// Notion is an in-memory archive.NotionClient
+type Notion struct {
+	mu sync.Mutex
+
+	// Pages holds every page keyed by page ID
+	Pages map[string]*Page
+	// Created holds the parameters of every created page in order
+	Created []notion.CreatePageParams
+	// Databases holds the databases keyed by database ID
+	Databases map[string]notion.Database
+	// Err is returned by every call when set
+	Err error
+}
+
+// Page is a page stored by Notion
+type Page struct {
+	ID         string
+	DatabaseID string
+	Archived   bool
+	Properties notion.DatabasePageProperties
+	Children   []notion.Block
+}
+
+func (p *Page) page() notion.Page {
+	return notion.Page{
+		ID:         p.ID,
+		URL:        "https://www.notion.so/" + p.ID,
+		Parent:     notion.Parent{Type: notion.ParentTypeDatabase, DatabaseID: p.DatabaseID},
+		Archived:   p.Archived,
+		Properties: p.Properties,
+	}
+}
+
+// CreatePage stores the page
+func (n *Notion) CreatePage(ctx context.Context, params notion.CreatePageParams) (notion.Page, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.Err != nil {
+		return notion.Page{}, n.Err
+	}
+	if n.Pages == nil {
+		n.Pages = map[string]*Page{}
+	}
+
+	p := &Page{
+		ID:         fmt.Sprintf("page-%d", len(n.Created)+1),
+		DatabaseID: params.ParentID,
+		Properties: notion.DatabasePageProperties{},
+		Children:   params.Children,
+	}
+	if params.DatabasePageProperties != nil {
+		for k, v := range *params.DatabasePageProperties {
+			p.Properties[k] = v
+		}
+	}
+
+	n.Pages[p.ID] = p
+	n.Created = append(n.Created, params)
+	return p.page(), nil
+}
+
+// UpdatePage merges the properties into the stored page
+func (n *Notion) UpdatePage(ctx context.Context, pageID string, params notion.UpdatePageParams) (notion.Page, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.Err != nil {
+		return notion.Page{}, n.Err
+	}
+
+	p, ok := n.Pages[pageID]
+	if !ok {
+		return notion.Page{}, ErrObjectNotFound
+	}
+	for k, v := range params.DatabasePageProperties {
+		p.Properties[k] = v
+	}
+	if params.Archived != nil {
+		p.Archived = *params.Archived
+	}
+	return p.page(), nil
+}
+
+// QueryDatabase returns the non-archived pages of the database that match the text filter of the query
+func (n *Notion) QueryDatabase(ctx context.Context, id string, query *notion.DatabaseQuery) (notion.DatabaseQueryResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.Err != nil {
+		return notion.DatabaseQueryResponse{}, n.Err
+	}
+
+	var result notion.DatabaseQueryResponse
+	for _, p := range n.Pages {
+		if p.DatabaseID != id || p.Archived {
+			continue
+		}
+		if query != nil && query.Filter != nil && !matchFilter(p, query.Filter) {
+			continue
+		}
+		result.Results = append(result.Results, p.page())
+	}
+	sort.Slice(result.Results, func(i, j int) bool { return result.Results[i].ID < result.Results[j].ID })
+	return result, nil
+}
+
+// AppendBlockChildren appends the blocks to the stored page
+func (n *Notion) AppendBlockChildren(ctx context.Context, blockID string, children []notion.Block) (notion.BlockChildrenResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.Err != nil {
+		return notion.BlockChildrenResponse{}, n.Err
+	}
+
+	p, ok := n.Pages[blockID]
+	if !ok {
+		return notion.BlockChildrenResponse{}, ErrObjectNotFound
+	}
+	p.Children = append(p.Children, children...)
+	return notion.BlockChildrenResponse{Results: children}, nil
+}
+
+// FindDatabaseByID returns the stored database
+func (n *Notion) FindDatabaseByID(ctx context.Context, id string) (notion.Database, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.Err != nil {
+		return notion.Database{}, n.Err
+	}
+	db, ok := n.Databases[id]
+	if !ok {
+		return notion.Database{}, ErrObjectNotFound
+	}
+	return db, nil
+}
+
+func matchFilter(p *Page, filter *notion.DatabaseQueryFilter) bool {
+	if filter.Text == nil {
+		return true
+	}
+
+	prop := p.Properties[filter.Property]
+	var sb strings.Builder
+	for _, rt := range append(prop.Title, prop.RichText...) {
+		if rt.Text != nil {
+			sb.WriteString(rt.Text.Content)
+		}
+	}
+	return sb.String() == filter.Text.Equals
+}
+
+// OpenAI is an in-memory archive.OpenAIClient
+type OpenAI struct {
+	mu sync.Mutex
+
+	// Reply is returned as the content of every completion
+	Reply string
+	// Requests holds every received request in order
+	Requests []openai.ChatCompletionRequest
+	// Err is returned by every call when set
+	Err error
+}
+
+// CreateChatCompletion records the request and returns Reply
+func (o *OpenAI) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.Requests = append(o.Requests, request)
+	if o.Err != nil {
+		return openai.ChatCompletionResponse{}, o.Err
+	}
+
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: o.Reply}},
+		},
+	}, nil
+}
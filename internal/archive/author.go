@@ -0,0 +1,105 @@
+package archive
+
+import (
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/slack-go/slack"
+)
+
+// MessageTimeLayout is the layout of the time shown on every archived message
+const MessageTimeLayout = "2006-01-02 15:04"
+
+// messageHeader returns "<author>  <time>\n" with the time linked to the message
+func (a *Archiver) messageHeader(message slack.Message, link string) []notion.RichText {
+	header := []notion.RichText{
+		{
+			Type:        notion.RichTextTypeText,
+			Annotations: &notion.Annotations{Bold: true, Color: notion.ColorDefault},
+			Text:        &notion.Text{Content: a.authorName(message)},
+		},
+		{
+			Type: notion.RichTextTypeText,
+			Text: &notion.Text{Content: "  "},
+		},
+	}
+
+	timeText := &notion.Text{Content: a.formatTimestamp(message.Timestamp)}
+	if link != "" {
+		timeText.Link = &notion.Link{URL: link}
+	}
+	header = append(header,
+		notion.RichText{
+			Type:        notion.RichTextTypeText,
+			Annotations: &notion.Annotations{Color: notion.ColorGray},
+			Text:        timeText,
+		},
+		notion.RichText{
+			Type: notion.RichTextTypeText,
+			Text: &notion.Text{Content: "\n"},
+		},
+	)
+	return header
+}
+
+func (a *Archiver) authorName(message slack.Message) string {
+	if message.User != "" {
+		return a.directory.UserName(message.User)
+	}
+	if message.BotProfile != nil && message.BotProfile.Name != "" {
+		return message.BotProfile.Name
+	}
+	return firstNonEmpty(message.Username, message.BotID, "unknown")
+}
+
+func (a *Archiver) formatTimestamp(ts string) string {
+	t, err := ParseTimestamp(ts)
+	if err != nil {
+		return ts
+	}
+
+	loc := a.options.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(MessageTimeLayout)
+}
+
+// ParseTimestamp converts a Slack message timestamp such as "1680000000.000100" to time.Time
+func ParseTimestamp(ts string) (time.Time, error) {
+	sec, frac, _ := strings.Cut(ts, ".")
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var usec int64
+	if frac != "" {
+		if usec, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(s, usec*int64(time.Microsecond)), nil
+}
+
+// ReplyPermalink derives the permalink of a reply from the permalink of the thread, so that
+// archiving a thread does not call chat.getPermalink for every message.
+// It returns parentPermalink unchanged when the format is not recognized.
+func ReplyPermalink(parentPermalink string, channel string, ts string, threadTS string) string {
+	if ts == threadTS {
+		return parentPermalink
+	}
+
+	u, err := url.Parse(parentPermalink)
+	if err != nil || !strings.HasPrefix(path.Base(u.Path), "p") {
+		return parentPermalink
+	}
+
+	u.Path = path.Join(path.Dir(u.Path), "p"+strings.Replace(ts, ".", "", 1))
+	u.RawQuery = url.Values{"thread_ts": {threadTS}, "cid": {channel}}.Encode()
+	return u.String()
+}
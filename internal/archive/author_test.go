@@ -0,0 +1,202 @@
+package archive_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive/archivetest"
+	"github.com/slack-go/slack"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		ts      string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "with microseconds", ts: "1680000000.000100", want: time.Date(2023, 3, 28, 10, 40, 0, 100000, time.UTC)},
+		{name: "without fraction", ts: "1680000000", want: time.Date(2023, 3, 28, 10, 40, 0, 0, time.UTC)},
+		{name: "invalid seconds", ts: "abc.000100", wantErr: true},
+		{name: "invalid fraction", ts: "1680000000.abc", wantErr: true},
+		{name: "empty", ts: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := archive.ParseTimestamp(tt.ts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("err = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTimestamp(%q) = %v, want %v", tt.ts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplyPermalink(t *testing.T) {
+	const parent = "https://example.slack.com/archives/C1/p1680000000000100"
+
+	tests := []struct {
+		name      string
+		permalink string
+		ts        string
+		want      string
+	}{
+		{name: "parent message", permalink: parent, ts: parentTS, want: parent},
+		{
+			name:      "reply",
+			permalink: parent,
+			ts:        "1680000001.000200",
+			want:      "https://example.slack.com/archives/C1/p1680000001000200?cid=C1&thread_ts=1680000000.000100",
+		},
+		{
+			name:      "parent permalink with a query",
+			permalink: parent + "?thread_ts=1&cid=C9",
+			ts:        "1680000001.000200",
+			want:      "https://example.slack.com/archives/C1/p1680000001000200?cid=C1&thread_ts=1680000000.000100",
+		},
+		{
+			name:      "unrecognized permalink",
+			permalink: "https://example.slack.com/archives/C1/1680000000000100",
+			ts:        "1680000001.000200",
+			want:      "https://example.slack.com/archives/C1/1680000000000100",
+		},
+		{name: "invalid URL", permalink: "://example", ts: "1680000001.000200", want: "://example"},
+		{name: "no permalink", permalink: "", ts: "1680000001.000200", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := archive.ReplyPermalink(tt.permalink, channel, tt.ts, parentTS); got != tt.want {
+				t.Errorf("ReplyPermalink = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddPageToNotionDBShowsAuthorAndTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone database is not available: %v", err)
+	}
+	const permalink = "https://example.slack.com/archives/C1/p1680000000000100"
+
+	tests := []struct {
+		name     string
+		message  slack.Msg
+		location *time.Location
+		// wantAuthor, wantTime and wantLink are the header of the message
+		wantAuthor string
+		wantTime   string
+		wantLink   string
+	}{
+		{
+			name:       "user in UTC",
+			message:    slack.Msg{User: "U1", Timestamp: parentTS},
+			wantAuthor: "alice",
+			wantTime:   "2023-03-28 10:40",
+			wantLink:   permalink,
+		},
+		{
+			name:       "user in the configured timezone",
+			message:    slack.Msg{User: "U1", Timestamp: parentTS},
+			location:   tokyo,
+			wantAuthor: "alice",
+			wantTime:   "2023-03-28 19:40",
+			wantLink:   permalink,
+		},
+		{
+			name:       "reply across midnight",
+			message:    slack.Msg{User: "U1", Timestamp: "1680015600.000200"},
+			location:   tokyo,
+			wantAuthor: "alice",
+			wantTime:   "2023-03-29 00:00",
+			wantLink:   "https://example.slack.com/archives/C1/p1680015600000200?cid=C1&thread_ts=1680000000.000100",
+		},
+		{
+			name:       "unknown user",
+			message:    slack.Msg{User: "U9", Timestamp: parentTS},
+			wantAuthor: "U9",
+			wantTime:   "2023-03-28 10:40",
+			wantLink:   permalink,
+		},
+		{
+			name:       "bot profile",
+			message:    slack.Msg{BotID: "B1", Username: "ci", BotProfile: &slack.BotProfile{Name: "GitHub"}, Timestamp: parentTS},
+			wantAuthor: "GitHub",
+			wantTime:   "2023-03-28 10:40",
+			wantLink:   permalink,
+		},
+		{
+			name:       "bot username",
+			message:    slack.Msg{BotID: "B1", Username: "ci", Timestamp: parentTS},
+			wantAuthor: "ci",
+			wantTime:   "2023-03-28 10:40",
+			wantLink:   permalink,
+		},
+		{
+			name:       "bot ID",
+			message:    slack.Msg{BotID: "B1", Timestamp: parentTS},
+			wantAuthor: "B1",
+			wantTime:   "2023-03-28 10:40",
+			wantLink:   permalink,
+		},
+		{
+			name:       "no author",
+			message:    slack.Msg{Timestamp: parentTS},
+			wantAuthor: "unknown",
+			wantTime:   "2023-03-28 10:40",
+			wantLink:   permalink,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &archivetest.Slack{
+				Users: map[string]*slack.User{
+					"U1": {ID: "U1", Name: "alice.smith", Profile: slack.UserProfile{DisplayName: "alice"}},
+				},
+			}
+			n := newNotion()
+			a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{Routes: routes, Location: tt.location})
+
+			message := slack.Message{Msg: tt.message}
+			message.Text = "hello"
+			message.ThreadTimestamp = parentTS
+			thread := archive.Thread{Channel: channel, Timestamp: parentTS, Permalink: permalink, Messages: []slack.Message{message}}
+			if _, err := a.AddPageToNotionDB(context.Background(), routes[0], thread, archive.Digest{}); err != nil {
+				t.Fatal(err)
+			}
+
+			text := onlyPage(t, n).Children[1].Callout.Text
+			if len(text) < 4 {
+				t.Fatalf("callout = %q, want the header and the message", richText(text))
+			}
+			if got := text[0].Text.Content; got != tt.wantAuthor {
+				t.Errorf("author = %q, want %q", got, tt.wantAuthor)
+			}
+			if text[0].Annotations == nil || !text[0].Annotations.Bold {
+				t.Errorf("author is not bold")
+			}
+			if got := text[2].Text.Content; got != tt.wantTime {
+				t.Errorf("time = %q, want %q", got, tt.wantTime)
+			}
+			if link := text[2].Text.Link; link == nil || link.URL != tt.wantLink {
+				t.Errorf("time link = %+v, want %q", link, tt.wantLink)
+			}
+			if got := richText(text[4:]); got != "hello" {
+				t.Errorf("text = %q, want the message after the header", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package archive
+
+import (
+	"context"
+	"log"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// Request limits of the Notion API
+// https://developers.notion.com/reference/request-limits
+const (
+	maxRichTextLength    = 2000
+	maxRichTextElements  = 100
+	maxChildrenPerBlock  = 100
+	maxBlocksPerRequest  = 1000
+	maxTopLevelPerAppend = 100
+)
+
+// createPageInBatches creates the page with the first batch of children and appends the rest
+// with the block children API. When appending fails, the partial page is archived so that
+// archiving the thread again creates the whole page instead of keeping the partial one
+func (a *Archiver) createPageInBatches(ctx context.Context, params notion.CreatePageParams) (notion.Page, error) {
+	batches := batchBlocks(NormalizeBlocks(params.Children))
+
+	params.Children = nil
+	if len(batches) != 0 {
+		params.Children = batches[0]
+	}
+	page, err := a.notion.CreatePage(ctx, params)
+	if err != nil {
+		return page, err
+	}
+
+	if len(batches) > 1 {
+		if err := a.appendBatches(ctx, page.ID, batches[1:]); err != nil {
+			archived := true
+			if _, archiveErr := a.notion.UpdatePage(ctx, page.ID, notion.UpdatePageParams{Archived: &archived}); archiveErr != nil {
+				log.Printf("[ERROR] failed to archive the partial page %s: %v", page.ID, archiveErr)
+			}
+			return notion.Page{}, err
+		}
+	}
+	return page, nil
+}
+
+// AppendBlocks appends blocks to the page or block, splitting them to respect the Notion limits
+func (a *Archiver) AppendBlocks(ctx context.Context, blockID string, blocks []notion.Block) error {
+	return a.appendBatches(ctx, blockID, batchBlocks(NormalizeBlocks(blocks)))
+}
+
+func (a *Archiver) appendBatches(ctx context.Context, blockID string, batches [][]notion.Block) error {
+	for _, batch := range batches {
+		if _, err := a.notion.AppendBlockChildren(ctx, blockID, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchBlocks splits blocks into batches of at most 100 top level blocks and 1000 blocks in total
+func batchBlocks(blocks []notion.Block) [][]notion.Block {
+	var (
+		batches [][]notion.Block
+		current []notion.Block
+		count   int
+	)
+	for _, b := range blocks {
+		n := countBlocks(b)
+		if len(current) != 0 && (len(current) == maxTopLevelPerAppend || count+n > maxBlocksPerRequest) {
+			batches = append(batches, current)
+			current, count = nil, 0
+		}
+		current = append(current, b)
+		count += n
+	}
+	if len(current) != 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+func countBlocks(b notion.Block) int {
+	n := 1
+	for _, child := range blockChildren(b) {
+		n += countBlocks(child)
+	}
+	return n
+}
+
+// NormalizeBlocks splits text longer than 2000 characters into several rich text elements,
+// splits blocks with more than 100 rich text elements into several blocks of the same type,
+// and moves children beyond the 100th next to their parent
+func NormalizeBlocks(blocks []notion.Block) []notion.Block {
+	var normalized []notion.Block
+	for _, b := range blocks {
+		children := NormalizeBlocks(blockChildren(b))
+		var overflow []notion.Block
+		if len(children) > maxChildrenPerBlock {
+			children, overflow = children[:maxChildrenPerBlock], children[maxChildrenPerBlock:]
+		}
+
+		text := SplitRichText(blockText(b))
+		if len(text) <= maxRichTextElements {
+			normalized = append(normalized, withTextAndChildren(b, text, children))
+		} else {
+			for len(text) > maxRichTextElements {
+				normalized = append(normalized, withTextAndChildren(b, text[:maxRichTextElements], nil))
+				text = text[maxRichTextElements:]
+			}
+			normalized = append(normalized, withTextAndChildren(b, text, children))
+		}
+		normalized = append(normalized, overflow...)
+	}
+	return normalized
+}
+
+// SplitRichText splits every text element longer than 2000 characters (counted in UTF-16 like Notion)
+func SplitRichText(rich []notion.RichText) []notion.RichText {
+	var split []notion.RichText
+	for _, rt := range rich {
+		if rt.Text == nil || utf16Len(rt.Text.Content) <= maxRichTextLength {
+			split = append(split, rt)
+			continue
+		}
+
+		for _, part := range splitText(rt.Text.Content, maxRichTextLength) {
+			text := *rt.Text
+			text.Content = part
+			copied := rt
+			copied.Text = &text
+			split = append(split, copied)
+		}
+	}
+	return split
+}
+
+// TruncateText shortens text to at most limit UTF-16 code units
+func TruncateText(text string, limit int) string {
+	return text[:prefixSize(text, limit)]
+}
+
+// splitText splits text into parts of at most limit UTF-16 code units.
+// A character wider than limit becomes a part of its own so that the loop always advances
+func splitText(text string, limit int) []string {
+	var parts []string
+	for text != "" {
+		size := prefixSize(text, limit)
+		if size == 0 {
+			_, size = utf8.DecodeRuneInString(text)
+		}
+		parts = append(parts, text[:size])
+		text = text[size:]
+	}
+	return parts
+}
+
+// prefixSize returns the byte length of the longest prefix of text within limit UTF-16 code units
+func prefixSize(text string, limit int) int {
+	n, size := 0, 0
+	for size < len(text) {
+		r, w := utf8.DecodeRuneInString(text[size:])
+		units := len(utf16.Encode([]rune{r}))
+		if n+units > limit {
+			break
+		}
+		n += units
+		size += w
+	}
+	return size
+}
+
+func utf16Len(text string) int {
+	n := 0
+	for _, r := range text {
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
+
+// blockText returns the rich text of the block types produced by the archiver
+func blockText(b notion.Block) []notion.RichText {
+	if rtb := richTextBlock(&b); rtb != nil {
+		return rtb.Text
+	}
+	return nil
+}
+
+func blockChildren(b notion.Block) []notion.Block {
+	if rtb := richTextBlock(&b); rtb != nil {
+		return rtb.Children
+	}
+	return nil
+}
+
+// withTextAndChildren returns a copy of the block with the text and children replaced
+func withTextAndChildren(b notion.Block, text []notion.RichText, children []notion.Block) notion.Block {
+	switch b.Type {
+	case notion.BlockTypeParagraph:
+		b.Paragraph = &notion.RichTextBlock{Text: text, Children: children}
+	case notion.BlockTypeQuote:
+		b.Quote = &notion.RichTextBlock{Text: text, Children: children}
+	case notion.BlockTypeBulletedListItem:
+		b.BulletedListItem = &notion.RichTextBlock{Text: text, Children: children}
+	case notion.BlockTypeNumberedListItem:
+		b.NumberedListItem = &notion.RichTextBlock{Text: text, Children: children}
+	case notion.BlockTypeToggle:
+		b.Toggle = &notion.RichTextBlock{Text: text, Children: children}
+	case notion.BlockTypeToDo:
+		todo := *b.ToDo
+		todo.RichTextBlock = notion.RichTextBlock{Text: text, Children: children}
+		b.ToDo = &todo
+	case notion.BlockTypeCallout:
+		callout := *b.Callout
+		callout.RichTextBlock = notion.RichTextBlock{Text: text, Children: children}
+		b.Callout = &callout
+	case notion.BlockTypeCode:
+		code := *b.Code
+		code.RichTextBlock = notion.RichTextBlock{Text: text, Children: children}
+		b.Code = &code
+	}
+	return b
+}
+
+func richTextBlock(b *notion.Block) *notion.RichTextBlock {
+	switch b.Type {
+	case notion.BlockTypeParagraph:
+		return b.Paragraph
+	case notion.BlockTypeQuote:
+		return b.Quote
+	case notion.BlockTypeBulletedListItem:
+		return b.BulletedListItem
+	case notion.BlockTypeNumberedListItem:
+		return b.NumberedListItem
+	case notion.BlockTypeToggle:
+		return b.Toggle
+	case notion.BlockTypeToDo:
+		if b.ToDo != nil {
+			return &b.ToDo.RichTextBlock
+		}
+	case notion.BlockTypeCallout:
+		if b.Callout != nil {
+			return &b.Callout.RichTextBlock
+		}
+	case notion.BlockTypeCode:
+		if b.Code != nil {
+			return &b.Code.RichTextBlock
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package archive_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+)
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{name: "short", text: "abc", limit: 5, want: "abc"},
+		{name: "exact", text: "abc", limit: 3, want: "abc"},
+		{name: "long", text: "abcdef", limit: 3, want: "abc"},
+		{name: "zero limit", text: "abc", limit: 0, want: ""},
+		{name: "negative limit", text: "abc", limit: -1, want: ""},
+		{name: "surrogate pair wider than limit", text: "😀…", limit: 1, want: ""},
+		{name: "surrogate pair is not split", text: "a😀b", limit: 2, want: "a"},
+		{name: "multibyte", text: "あいうえお", limit: 3, want: "あいう"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := archive.TruncateText(tt.text, tt.limit); got != tt.want {
+				t.Errorf("TruncateText(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitRichText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{name: "short", text: "abc", want: []int{3}},
+		{name: "limit", text: strings.Repeat("a", 2000), want: []int{2000}},
+		{name: "long", text: strings.Repeat("a", 4500), want: []int{2000, 2000, 500}},
+		// 😀 is two UTF-16 code units, so the 1000th one does not fit in the first element
+		{name: "surrogate pairs", text: "a" + strings.Repeat("😀", 1000), want: []int{1999, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split := archive.SplitRichText([]notion.RichText{{Type: notion.RichTextTypeText, Text: &notion.Text{Content: tt.text}}})
+
+			var joined strings.Builder
+			var got []int
+			for _, rt := range split {
+				joined.WriteString(rt.Text.Content)
+				got = append(got, len(utf16.Encode([]rune(rt.Text.Content))))
+			}
+			if joined.String() != tt.text {
+				t.Errorf("joined text differs from the original")
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("lengths = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("lengths = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package archive
+
+import (
+	"context"
+	"io"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/sashabaranov/go-openai"
+	"github.com/slack-go/slack"
+)
+
+// SlackClient is the subset of *slack.Client used by the pipeline
+type SlackClient interface {
+	GetConversationReplies(params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error)
+	GetPermalink(params *slack.PermalinkParameters) (string, error)
+	GetReactions(item slack.ItemRef, params slack.GetReactionsParameters) ([]slack.ItemReaction, error)
+	GetUserInfo(user string) (*slack.User, error)
+	GetConversationInfo(channelID string, includeLocale bool) (*slack.Channel, error)
+	GetUserGroups(options ...slack.GetUserGroupsOption) ([]slack.UserGroup, error)
+	GetFileContext(ctx context.Context, downloadURL string, writer io.Writer) error
+	PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error)
+	PostEphemeralContext(ctx context.Context, channelID string, userID string, options ...slack.MsgOption) (string, error)
+}
+
+// NotionClient is the subset of *notion.Client used by the pipeline
+type NotionClient interface {
+	CreatePage(ctx context.Context, params notion.CreatePageParams) (notion.Page, error)
+	UpdatePage(ctx context.Context, pageID string, params notion.UpdatePageParams) (notion.Page, error)
+	QueryDatabase(ctx context.Context, id string, query *notion.DatabaseQuery) (notion.DatabaseQueryResponse, error)
+	AppendBlockChildren(ctx context.Context, blockID string, children []notion.Block) (notion.BlockChildrenResponse, error)
+	FindDatabaseByID(ctx context.Context, id string) (notion.Database, error)
+}
+
+// OpenAIClient is the subset of *openai.Client used by the pipeline
+type OpenAIClient interface {
+	CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
+
+var (
+	_ SlackClient  = (*slack.Client)(nil)
+	_ NotionClient = (*notion.Client)(nil)
+	_ OpenAIClient = (*openai.Client)(nil)
+)
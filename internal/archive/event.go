@@ -0,0 +1,85 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+// HandleEvent processes the body of an Events API callback
+func (a *Archiver) HandleEvent(ctx context.Context, body []byte) error {
+	eventsAPIEvent, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Start slackevents Handler")
+	switch event := eventsAPIEvent.InnerEvent.Data.(type) {
+	case *slackevents.ReactionAddedEvent:
+		log.Printf("[INFO] Start ReactionAddedEvent Handler")
+		if err := a.ReactionAddedEventHandler(ctx, event); err != nil {
+			return err
+		}
+	case *slackevents.ReactionRemovedEvent:
+		log.Printf("[INFO] Start ReactionRemovedEvent Handler")
+		if err := a.ReactionRemovedEventHandler(ctx, event); err != nil {
+			return err
+		}
+	case *slackevents.MessageEvent:
+		if err := a.MessageEventHandler(ctx, event); err != nil {
+			return err
+		}
+	default:
+		log.Printf("[INFO] unknow slackevents: %s", event)
+	}
+	log.Printf("[INFO] Done slackevents Handler")
+	return nil
+}
+
+// SkipEvent reports whether the event is acknowledged without enqueueing a job.
+// Every message and reaction of the subscribed channels is delivered, but only thread replies
+// are mirrored and only the trigger reactions of the routes archive threads
+func (a *Archiver) SkipEvent(event slackevents.EventsAPIEvent) bool {
+	switch e := event.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		return !IsMirroredReply(e)
+	case *slackevents.ReactionAddedEvent:
+		_, ok := a.FindRoute(e.Reaction, e.Item.Channel)
+		return !ok
+	case *slackevents.ReactionRemovedEvent:
+		_, ok := a.FindRoute(e.Reaction, e.Item.Channel)
+		return !ok
+	}
+	return false
+}
+
+// Retry is the X-Slack-Retry-Num / X-Slack-Retry-Reason headers of a redelivered event
+type Retry struct {
+	Num    int
+	Reason string
+}
+
+// ParseRetry returns the retry headers of the request, or false when it is the first delivery
+func ParseRetry(header http.Header) (Retry, bool) {
+	num := header.Get("X-Slack-Retry-Num")
+	if num == "" {
+		return Retry{}, false
+	}
+
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return Retry{}, false
+	}
+	return Retry{Num: n, Reason: header.Get("X-Slack-Retry-Reason")}, true
+}
+
+// AlreadyAccepted reports whether the original delivery reached us and was enqueued.
+// Slack only retries with "http_timeout" when we responded too late, which never
+// drops the original job, so such retries are acknowledged without processing.
+func (r Retry) AlreadyAccepted() bool {
+	return r.Reason == "http_timeout"
+}
@@ -0,0 +1,100 @@
+package archive_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive/archivetest"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestParseRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  http.Header
+		want    archive.Retry
+		wantOK  bool
+		wantAck bool
+	}{
+		{name: "first delivery", header: http.Header{}},
+		{
+			name:    "timeout",
+			header:  http.Header{"X-Slack-Retry-Num": {"1"}, "X-Slack-Retry-Reason": {"http_timeout"}},
+			want:    archive.Retry{Num: 1, Reason: "http_timeout"},
+			wantOK:  true,
+			wantAck: true,
+		},
+		{
+			name:   "http error",
+			header: http.Header{"X-Slack-Retry-Num": {"2"}, "X-Slack-Retry-Reason": {"http_error"}},
+			want:   archive.Retry{Num: 2, Reason: "http_error"},
+			wantOK: true,
+		},
+		{
+			name:   "connection failure",
+			header: http.Header{"X-Slack-Retry-Num": {"3"}, "X-Slack-Retry-Reason": {"connection_failed"}},
+			want:   archive.Retry{Num: 3, Reason: "connection_failed"},
+			wantOK: true,
+		},
+		{
+			name:   "without reason",
+			header: http.Header{"X-Slack-Retry-Num": {"1"}},
+			want:   archive.Retry{Num: 1},
+			wantOK: true,
+		},
+		{name: "invalid number", header: http.Header{"X-Slack-Retry-Num": {"one"}, "X-Slack-Retry-Reason": {"http_timeout"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := archive.ParseRetry(tt.header)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("retry = %+v, want %+v", got, tt.want)
+			}
+			if got.AlreadyAccepted() != tt.wantAck {
+				t.Errorf("AlreadyAccepted() = %v, want %v", got.AlreadyAccepted(), tt.wantAck)
+			}
+		})
+	}
+}
+
+func TestSkipEvent(t *testing.T) {
+	a := archive.New(&archivetest.Slack{}, &archivetest.Notion{}, &archivetest.OpenAI{}, archive.Options{
+		Routes: []config.Route{
+			{Reaction: "bug", Database: "db-1", Channels: []string{"C1"}},
+			{Reaction: "memo", Database: "db-2"},
+		},
+	})
+	item := func(channel string) slackevents.Item {
+		return slackevents.Item{Channel: channel, Timestamp: "1.1"}
+	}
+
+	tests := []struct {
+		name  string
+		event interface{}
+		want  bool
+	}{
+		{name: "trigger reaction", event: &slackevents.ReactionAddedEvent{Reaction: "memo", Item: item("C2")}},
+		{name: "channel scoped trigger reaction", event: &slackevents.ReactionAddedEvent{Reaction: "bug", Item: item("C1")}},
+		{name: "trigger reaction of another channel", event: &slackevents.ReactionAddedEvent{Reaction: "bug", Item: item("C2")}, want: true},
+		{name: "other reaction", event: &slackevents.ReactionAddedEvent{Reaction: "thumbsup", Item: item("C1")}, want: true},
+		{name: "removed trigger reaction", event: &slackevents.ReactionRemovedEvent{Reaction: "memo", Item: item("C1")}},
+		{name: "removed other reaction", event: &slackevents.ReactionRemovedEvent{Reaction: "thumbsup", Item: item("C1")}, want: true},
+		{name: "thread reply", event: &slackevents.MessageEvent{Channel: "C1", TimeStamp: "1.2", ThreadTimeStamp: "1.1"}},
+		{name: "top-level message", event: &slackevents.MessageEvent{Channel: "C1", TimeStamp: "1.2"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := slackevents.EventsAPIEvent{InnerEvent: slackevents.EventsAPIInnerEvent{Data: tt.event}}
+			if got := a.SkipEvent(event); got != tt.want {
+				t.Errorf("SkipEvent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
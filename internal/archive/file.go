@@ -0,0 +1,132 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/slack-go/slack"
+)
+
+// maxConcurrentUploads limits the files copied at the same time, so large threads do not
+// hit the Slack rate limit or exhaust the Lambda memory
+const maxConcurrentUploads = 4
+
+// CopyFiles copies every file shared in the messages to Options.Storage and
+// returns their public URLs keyed by Slack file ID. Files that fail to copy are
+// logged and omitted, so they fall back to a link to Slack.
+func (a *Archiver) CopyFiles(ctx context.Context, messages []slack.Message) map[string]string {
+	urls := map[string]string{}
+	if a.options.Storage == nil {
+		return urls
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, maxConcurrentUploads)
+	)
+	for _, message := range messages {
+		for _, file := range message.Files {
+			if !copyable(file) {
+				continue
+			}
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(file slack.File) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				url, err := a.copyFile(ctx, file)
+				if err != nil {
+					log.Printf("[ERROR] Failed to copy file %s: %v", file.ID, err)
+					return
+				}
+				mu.Lock()
+				urls[file.ID] = url
+				mu.Unlock()
+			}(file)
+		}
+	}
+	wg.Wait()
+
+	return urls
+}
+
+// copyFile streams the file from Slack to the storage
+func (a *Archiver) copyFile(ctx context.Context, file slack.File) (string, error) {
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(a.slack.GetFileContext(ctx, downloadURL(file), w))
+	}()
+	defer r.Close()
+
+	// Keyed by file ID so that archiving the thread again overwrites the same object
+	key := "slack-files/" + file.ID + "/" + strings.ReplaceAll(file.Name, "/", "_")
+	return a.options.Storage.Put(ctx, key, file.Mimetype, r)
+}
+
+func copyable(file slack.File) bool {
+	switch file.Mode {
+	case "tombstone", "hidden_by_limit", "external":
+		return false
+	}
+	return downloadURL(file) != ""
+}
+
+func downloadURL(file slack.File) string {
+	if file.URLPrivateDownload != "" {
+		return file.URLPrivateDownload
+	}
+	return file.URLPrivate
+}
+
+// fileBlocks converts the files of the message to Image, PDF or File blocks.
+// Files without a copied URL become a paragraph that links to the file in Slack.
+func fileBlocks(files []slack.File, urls map[string]string) []notion.Block {
+	var blocks []notion.Block
+	for _, file := range files {
+		name := firstNonEmpty(file.Title, file.Name, file.ID)
+
+		url, ok := urls[file.ID]
+		if !ok {
+			link := firstNonEmpty(file.Permalink, file.URLPrivate)
+			text := &notion.Text{Content: "📎 " + name}
+			if link != "" {
+				text.Link = &notion.Link{URL: link}
+			}
+			blocks = append(blocks, notion.Block{
+				Object: "block",
+				Type:   notion.BlockTypeParagraph,
+				Paragraph: &notion.RichTextBlock{
+					Text: []notion.RichText{{Type: notion.RichTextTypeText, Text: text}},
+				},
+			})
+			continue
+		}
+
+		fileBlock := &notion.FileBlock{
+			Type:     notion.FileTypeExternal,
+			External: &notion.FileExternal{URL: url},
+			Caption:  []notion.RichText{{Type: notion.RichTextTypeText, Text: &notion.Text{Content: name}}},
+		}
+		block := notion.Block{Object: "block"}
+		switch {
+		case strings.HasPrefix(file.Mimetype, "image/"):
+			block.Type = notion.BlockTypeImage
+			block.Image = fileBlock
+		case file.Mimetype == "application/pdf":
+			block.Type = notion.BlockTypePDF
+			block.PDF = fileBlock
+		default:
+			block.Type = notion.BlockTypeFile
+			block.File = fileBlock
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
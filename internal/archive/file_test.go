@@ -0,0 +1,134 @@
+package archive_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive/archivetest"
+	"github.com/slack-go/slack"
+)
+
+// fileURL returns the type and URL of a file block, or the link of a paragraph that links to Slack
+func fileURL(b notion.Block) (notion.BlockType, string) {
+	switch b.Type {
+	case notion.BlockTypeImage:
+		return b.Type, b.Image.External.URL
+	case notion.BlockTypePDF:
+		return b.Type, b.PDF.External.URL
+	case notion.BlockTypeFile:
+		return b.Type, b.File.External.URL
+	case notion.BlockTypeParagraph:
+		if link := b.Paragraph.Text[0].Text.Link; link != nil {
+			return b.Type, link.URL
+		}
+	}
+	return b.Type, ""
+}
+
+func TestReactionAddedEventHandlerCopiesFiles(t *testing.T) {
+	const (
+		downloadURL = "https://files.slack.com/files-pri/T1-F1/download/report"
+		permalink   = "https://example.slack.com/files/U1/F1/report"
+	)
+	content := []byte("content")
+
+	tests := []struct {
+		name     string
+		file     slack.File
+		noStore  bool
+		missing  bool
+		wantType notion.BlockType
+		wantURL  string
+		// wantKey is the key of the stored object, empty when the file is not copied
+		wantKey string
+	}{
+		{
+			name:     "image",
+			file:     slack.File{ID: "F1", Name: "photo.png", Mimetype: "image/png"},
+			wantType: notion.BlockTypeImage,
+			wantURL:  "https://files.example.com/slack-files/F1/photo.png",
+			wantKey:  "slack-files/F1/photo.png",
+		},
+		{
+			name:     "pdf",
+			file:     slack.File{ID: "F1", Name: "report.pdf", Mimetype: "application/pdf"},
+			wantType: notion.BlockTypePDF,
+			wantURL:  "https://files.example.com/slack-files/F1/report.pdf",
+			wantKey:  "slack-files/F1/report.pdf",
+		},
+		{
+			name:     "other file with an unsafe name",
+			file:     slack.File{ID: "F1", Name: "a/b c.txt", Mimetype: "text/plain"},
+			wantType: notion.BlockTypeFile,
+			wantURL:  "https://files.example.com/slack-files/F1/a_b%20c.txt",
+			wantKey:  "slack-files/F1/a_b c.txt",
+		},
+		{
+			name:     "deleted file",
+			file:     slack.File{ID: "F1", Name: "photo.png", Mimetype: "image/png", Mode: "tombstone"},
+			wantType: notion.BlockTypeParagraph,
+			wantURL:  permalink,
+		},
+		{
+			name:     "download failure",
+			file:     slack.File{ID: "F1", Name: "photo.png", Mimetype: "image/png"},
+			missing:  true,
+			wantType: notion.BlockTypeParagraph,
+			wantURL:  permalink,
+		},
+		{
+			name:     "no storage",
+			file:     slack.File{ID: "F1", Name: "photo.png", Mimetype: "image/png"},
+			noStore:  true,
+			wantType: notion.BlockTypeParagraph,
+			wantURL:  permalink,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := tt.file
+			file.URLPrivateDownload = downloadURL
+			file.Permalink = permalink
+			messages := newThread(1)
+			messages[0].Files = []slack.File{file}
+
+			s := &archivetest.Slack{Files: map[string][]byte{}}
+			if !tt.missing {
+				s.Files[downloadURL] = content
+			}
+			s.AddThread(channel, messages...)
+			store := &archivetest.Storage{}
+			opts := archive.Options{Routes: routes, Storage: store}
+			if tt.noStore {
+				opts.Storage = nil
+			}
+			n := newNotion()
+			a := archive.New(s, n, &archivetest.OpenAI{}, opts)
+
+			if err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction)); err != nil {
+				t.Fatal(err)
+			}
+
+			children := onlyPage(t, n).Children[1].Callout.Children
+			if len(children) != 1 {
+				t.Fatalf("callout children = %d, want the file block", len(children))
+			}
+			if typ, url := fileURL(children[0]); typ != tt.wantType || url != tt.wantURL {
+				t.Errorf("file block = %s %s, want %s %s", typ, url, tt.wantType, tt.wantURL)
+			}
+
+			if tt.wantKey == "" {
+				if len(store.Objects) != 0 {
+					t.Errorf("stored %d objects, want none", len(store.Objects))
+				}
+				return
+			}
+			if got := string(store.Objects[tt.wantKey]); got != string(content) {
+				t.Errorf("object %s = %q, want %q", tt.wantKey, got, content)
+			}
+		})
+	}
+}
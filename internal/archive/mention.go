@@ -0,0 +1,182 @@
+package archive
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/mrkdwn"
+	"github.com/slack-go/slack"
+)
+
+// Directory resolves Slack user, channel and usergroup IDs to names with users.info,
+// conversations.info and usergroups.list, caching every answer in memory
+type Directory struct {
+	slack SlackClient
+	// notionUsers maps Slack profile emails to Notion user IDs
+	notionUsers map[string]string
+
+	mu         sync.Mutex
+	users      map[string]*slack.User
+	channels   map[string]string
+	usergroups map[string]string
+}
+
+// NewDirectory returns a Directory. Users whose email is in notionUsers are rendered as Notion person mentions
+func NewDirectory(slackClient SlackClient, notionUsers map[string]string) *Directory {
+	emails := make(map[string]string, len(notionUsers))
+	for email, id := range notionUsers {
+		emails[strings.ToLower(email)] = id
+	}
+
+	return &Directory{
+		slack:       slackClient,
+		notionUsers: emails,
+		users:       map[string]*slack.User{},
+		channels:    map[string]string{},
+	}
+}
+
+// User returns the Slack user of the ID
+func (d *Directory) User(id string) (*slack.User, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if u, ok := d.users[id]; ok {
+		return u, nil
+	}
+
+	u, err := d.slack.GetUserInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	d.users[id] = u
+	return u, nil
+}
+
+// UserName returns the display name of the user, falling back to the real name and the ID
+func (d *Directory) UserName(id string) string {
+	u, err := d.User(id)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user %s: %v", id, err)
+		return id
+	}
+	return firstNonEmpty(u.Profile.DisplayName, u.Profile.RealName, u.RealName, u.Name, id)
+}
+
+// ChannelName returns the name of the channel, or the ID when it cannot be resolved
+func (d *Directory) ChannelName(id string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if name, ok := d.channels[id]; ok {
+		return name
+	}
+
+	channel, err := d.slack.GetConversationInfo(id, false)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get channel %s: %v", id, err)
+		return id
+	}
+	d.channels[id] = channel.Name
+	return channel.Name
+}
+
+// UsergroupHandle returns the handle of the usergroup, or the ID when it cannot be resolved
+func (d *Directory) UsergroupHandle(id string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.usergroups == nil {
+		groups, err := d.slack.GetUserGroups()
+		if err != nil {
+			log.Printf("[ERROR] Failed to list usergroups: %v", err)
+			return id
+		}
+		d.usergroups = make(map[string]string, len(groups))
+		for _, g := range groups {
+			d.usergroups[g.ID] = firstNonEmpty(g.Handle, g.Name)
+		}
+	}
+
+	if handle, ok := d.usergroups[id]; ok {
+		return handle
+	}
+	return id
+}
+
+// NotionUserID returns the Notion user mapped to the Slack user by email
+func (d *Directory) NotionUserID(slackUserID string) (string, bool) {
+	if len(d.notionUsers) == 0 {
+		return "", false
+	}
+
+	u, err := d.User(slackUserID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get user %s: %v", slackUserID, err)
+		return "", false
+	}
+	id, ok := d.notionUsers[strings.ToLower(u.Profile.Email)]
+	return id, ok && u.Profile.Email != ""
+}
+
+// ResolveMention renders user mentions as Notion person mentions when mapped, and every other
+// mention as its readable name
+func (d *Directory) ResolveMention(m mrkdwn.Mention) notion.RichText {
+	if m.Kind == mrkdwn.MentionUser {
+		if id, ok := d.NotionUserID(m.ID); ok {
+			return notion.RichText{
+				Type: notion.RichTextTypeMention,
+				Mention: &notion.Mention{
+					Type: notion.MentionTypeUser,
+					User: &notion.User{ID: id, Type: notion.UserTypePerson},
+				},
+			}
+		}
+	}
+
+	return notion.RichText{
+		Type: notion.RichTextTypeText,
+		Text: &notion.Text{Content: d.MentionName(m)},
+	}
+}
+
+// MentionName returns the readable text of the mention such as "@alice" or "#general"
+func (d *Directory) MentionName(m mrkdwn.Mention) string {
+	var name string
+	switch m.Kind {
+	case mrkdwn.MentionUser:
+		if m.Label == "" {
+			name = d.UserName(m.ID)
+		}
+	case mrkdwn.MentionChannel:
+		if m.Label == "" {
+			name = d.ChannelName(m.ID)
+		}
+	case mrkdwn.MentionUsergroup:
+		name = d.UsergroupHandle(m.ID)
+	}
+	return mrkdwn.MentionText(m, name)
+}
+
+// textMentions renders every mention as plain text, for places where Notion mentions cannot be used
+type textMentions struct {
+	directory *Directory
+}
+
+func (t textMentions) ResolveMention(m mrkdwn.Mention) notion.RichText {
+	return notion.RichText{
+		Type: notion.RichTextTypeText,
+		Text: &notion.Text{Content: t.directory.MentionName(m)},
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
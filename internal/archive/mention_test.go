@@ -0,0 +1,88 @@
+package archive_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive/archivetest"
+	"github.com/slack-go/slack"
+)
+
+// richText concatenates the text of the rich text elements, with person mentions as "<person:ID>"
+func richText(text []notion.RichText) string {
+	var sb strings.Builder
+	for _, rt := range text {
+		switch {
+		case rt.Text != nil:
+			sb.WriteString(rt.Text.Content)
+		case rt.Mention != nil && rt.Mention.User != nil:
+			sb.WriteString("<person:" + rt.Mention.User.ID + ">")
+		}
+	}
+	return sb.String()
+}
+
+func TestReactionAddedEventHandlerResolvesMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		// want is the text of the message after the header
+		want string
+	}{
+		{name: "display name", text: "<@U2> thanks", want: "@bob thanks"},
+		{name: "real name", text: "cc <@U3>", want: "cc @Carol"},
+		{name: "notion user", text: "<@U1> please review", want: "<person:notion-alice> please review"},
+		{name: "unknown user", text: "<@U9>", want: "@U9"},
+		{name: "user label", text: "<@U9|dave>", want: "@dave"},
+		{name: "channel", text: "see <#C2>", want: "see #general"},
+		{name: "channel label", text: "see <#C9|random>", want: "see #random"},
+		{name: "unknown channel", text: "see <#C9>", want: "see #C9"},
+		{name: "usergroup", text: "<!subteam^S1> ping", want: "@devs ping"},
+		{name: "usergroup without handle", text: "<!subteam^S2>", want: "@Designers"},
+		{name: "unknown usergroup", text: "<!subteam^S9>", want: "@S9"},
+		{name: "special mention", text: "<!here> lunch", want: "@here lunch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := newThread(1)
+			messages[0].Text = tt.text
+			s := &archivetest.Slack{
+				Users: map[string]*slack.User{
+					"U1": {ID: "U1", Name: "alice", Profile: slack.UserProfile{DisplayName: "alice", Email: "Alice@example.com"}},
+					"U2": {ID: "U2", Name: "bob", Profile: slack.UserProfile{DisplayName: "bob", RealName: "Bob"}},
+					"U3": {ID: "U3", Name: "carol", Profile: slack.UserProfile{RealName: "Carol"}},
+				},
+				Channels: map[string]*slack.Channel{
+					"C2": {GroupConversation: slack.GroupConversation{Name: "general"}},
+				},
+				UserGroups: []slack.UserGroup{
+					{ID: "S1", Handle: "devs", Name: "Developers"},
+					{ID: "S2", Name: "Designers"},
+				},
+			}
+			s.AddThread(channel, messages...)
+			n := newNotion()
+			a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{
+				Routes:      routes,
+				NotionUsers: map[string]string{"alice@example.com": "notion-alice"},
+			})
+
+			if err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction)); err != nil {
+				t.Fatal(err)
+			}
+
+			callout := onlyPage(t, n).Children[1]
+			header, text, _ := strings.Cut(richText(callout.Callout.Text), "\n")
+			if !strings.HasPrefix(header, "alice  ") {
+				t.Errorf("header = %q, want the display name of the author", header)
+			}
+			if text != tt.want {
+				t.Errorf("text = %q, want %q", text, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/slack-go/slack"
+)
+
+// maxNotifyErrorLength keeps the error posted to Slack short
+const maxNotifyErrorLength = 200
+
+// Notify tells the thread, or only user with an ephemeral message, the URL of the archived page.
+// When err is not nil a short error is posted instead, so that users do not re-react blindly.
+// Failures to post are only logged
+func (a *Archiver) Notify(ctx context.Context, channel string, threadTimestamp string, user string, page notion.Page, err error) {
+	var text string
+	if err != nil {
+		text = fmt.Sprintf("⚠️ Notionへの保存に失敗しました: %s", TruncateText(err.Error(), maxNotifyErrorLength))
+	} else {
+		text = fmt.Sprintf("📝 Notionに保存しました: %s", page.URL)
+	}
+	options := []slack.MsgOption{
+		slack.MsgOptionText(text, true),
+		slack.MsgOptionTS(threadTimestamp),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	var postErr error
+	switch a.options.Notify.Mode {
+	case config.NotifyThread:
+		_, _, postErr = a.slack.PostMessageContext(ctx, channel, options...)
+	case config.NotifyEphemeral:
+		if user == "" {
+			return
+		}
+		_, postErr = a.slack.PostEphemeralContext(ctx, channel, user, options...)
+	default:
+		return
+	}
+	if postErr != nil {
+		log.Printf("[ERROR] Failed to notify %s: %v", ThreadKey(channel, threadTimestamp), postErr)
+	}
+}
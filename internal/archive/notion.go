@@ -0,0 +1,267 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/slack-go/slack"
+)
+
+// DefaultThreadIDProperty is the rich text property that stores Thread.Key
+const DefaultThreadIDProperty = "Slack Thread ID"
+
+// AddPageToNotionDB creates a page that contains the thread messages in the Notion database of the route.
+// When a page of the same thread already exists, only its properties are updated instead of creating a duplicate.
+// Concurrent invocations can both miss the existing page, so the database is queried again after
+// creating the page and only the earliest page of the thread is kept.
+func (a *Archiver) AddPageToNotionDB(ctx context.Context, route config.Route, thread Thread, digest Digest) (notion.Page, error) {
+	databaseID := route.Database
+
+	unlock := a.locks.lock(thread.Key())
+	defer unlock()
+
+	page, found, err := a.UpdateArchivedPage(ctx, route, thread, digest.Title)
+	if err != nil || found {
+		return page, err
+	}
+
+	title := digest.Title
+	if title == "" {
+		generated, err := a.TitleStrategy(route).Title(ctx, thread)
+		if err != nil {
+			return notion.Page{}, err
+		}
+		title = generated
+	}
+	notionTitle := plainRichText(title)
+
+	properties := a.mappedProperties(route, thread)
+	properties[a.titleProperty(databaseID)] = notion.DatabasePageProperty{Title: notionTitle}
+	properties[a.threadIDProperty()] = notion.DatabasePageProperty{RichText: plainRichText(thread.Key())}
+
+	children := []notion.Block{}
+	children = append(children, createSummarizedNotionCalloutBlock(digest.Summary, thread.Permalink))
+	children = append(children, a.extractionBlocks(digest)...)
+	children = append(children, a.ConvertSlackMessagesToNotionCalloutBlocks(thread)...)
+
+	params := notion.CreatePageParams{
+		ParentID:               databaseID,
+		ParentType:             notion.ParentTypeDatabase,
+		Title:                  notionTitle,
+		DatabasePageProperties: &properties,
+		Children:               children,
+	}
+
+	page, err = a.createPageInBatches(ctx, params)
+	if err != nil {
+		return page, err
+	}
+	return a.keepEarliestPage(ctx, databaseID, thread.Key(), page)
+}
+
+// UpdateArchivedPage updates the properties of the page of the thread when it already exists in the database of the route.
+// The body is left as it is, so callers can skip copying files and summarizing; the title is only replaced when
+// title is not empty, so that titles edited in Notion are kept. The status withdrawn by Options.ReactionRemoved is restored.
+// It returns false when the thread is not archived yet
+func (a *Archiver) UpdateArchivedPage(ctx context.Context, route config.Route, thread Thread, title string) (notion.Page, bool, error) {
+	existing, found, err := a.FindPageByThread(ctx, route.Database, thread.Key())
+	if err != nil || !found {
+		return notion.Page{}, false, err
+	}
+	log.Printf("[INFO] page for thread %s already exists: %s", thread.Key(), existing.ID)
+
+	properties := a.mappedProperties(route, thread)
+	if title != "" {
+		properties[a.titleProperty(route.Database)] = notion.DatabasePageProperty{Title: plainRichText(title)}
+	}
+	if status, ok := a.restoredStatus(existing); ok {
+		properties[a.options.ReactionRemoved.StatusProperty] = status
+	}
+	if len(properties) == 0 {
+		return existing, true, nil
+	}
+	page, err := a.notion.UpdatePage(ctx, existing.ID, notion.UpdatePageParams{DatabasePageProperties: properties})
+	return page, true, err
+}
+
+// keepEarliestPage archives page when another page of the thread was created before it, and returns the kept page
+func (a *Archiver) keepEarliestPage(ctx context.Context, databaseID string, threadKey string, page notion.Page) (notion.Page, error) {
+	earliest, found, err := a.FindPageByThread(ctx, databaseID, threadKey)
+	if err != nil {
+		return page, err
+	}
+	if !found || earliest.ID == page.ID {
+		return page, nil
+	}
+
+	log.Printf("[INFO] page for thread %s was created concurrently: keep %s, archive %s", threadKey, earliest.ID, page.ID)
+	archived := true
+	if _, err := a.notion.UpdatePage(ctx, page.ID, notion.UpdatePageParams{Archived: &archived}); err != nil {
+		return page, err
+	}
+	return earliest, nil
+}
+
+// FindPageByThread returns the page whose thread ID property equals threadKey.
+// When duplicates exist, the earliest created page (then the smallest ID) is returned so that
+// every invocation agrees on the same page
+func (a *Archiver) FindPageByThread(ctx context.Context, databaseID string, threadKey string) (notion.Page, bool, error) {
+	result, err := a.notion.QueryDatabase(ctx, databaseID, &notion.DatabaseQuery{
+		Filter: &notion.DatabaseQueryFilter{
+			Property: a.threadIDProperty(),
+			Text:     &notion.TextDatabaseQueryFilter{Equals: threadKey},
+		},
+		Sorts: []notion.DatabaseQuerySort{
+			{Timestamp: notion.SortTimeStampCreatedTime, Direction: notion.SortDirAsc},
+		},
+	})
+	if err != nil {
+		return notion.Page{}, false, err
+	}
+	if len(result.Results) == 0 {
+		return notion.Page{}, false, nil
+	}
+
+	earliest := result.Results[0]
+	for _, p := range result.Results[1:] {
+		// created_time is rounded to the minute, so the ID breaks ties
+		if p.CreatedTime.Before(earliest.CreatedTime) || (p.CreatedTime.Equal(earliest.CreatedTime) && p.ID < earliest.ID) {
+			earliest = p
+		}
+	}
+	return earliest, true, nil
+}
+
+func (a *Archiver) threadIDProperty() string {
+	if a.options.ThreadIDProperty != "" {
+		return a.options.ThreadIDProperty
+	}
+	return DefaultThreadIDProperty
+}
+
+// createSummarizedNotionCalloutBlock creates a notion.Callout block that contains summarized text and slack link.
+// The summary section is omitted when summarizedText is empty
+func createSummarizedNotionCalloutBlock(summarizedText string, slackLink string) notion.Block {
+	emoji := "📙"
+	text := []notion.RichText{
+		{
+			Type: notion.RichTextTypeText,
+			Text: &notion.Text{Content: "■Slackのやり取り\n"},
+		},
+		{
+			Type: notion.RichTextTypeText,
+			Text: &notion.Text{
+				Content: slackLink,
+				Link:    &notion.Link{URL: slackLink},
+			},
+		},
+	}
+	if summarizedText != "" {
+		text = append(text,
+			notion.RichText{
+				Type: notion.RichTextTypeText,
+				Text: &notion.Text{Content: "\n\n■要約\n\n"},
+			},
+			notion.RichText{
+				Type: notion.RichTextTypeText,
+				Text: &notion.Text{Content: summarizedText},
+			},
+		)
+	}
+	return notion.Block{
+		Object: "block",
+		Type:   notion.BlockTypeCallout,
+		Callout: &notion.Callout{
+			RichTextBlock: notion.RichTextBlock{Text: text},
+			Icon: &notion.Icon{
+				Type:  notion.IconTypeEmoji,
+				Emoji: &emoji,
+			},
+		},
+	}
+}
+
+// summaryFailedText is shown in the summary section when the summarization failed
+func summaryFailedText(err error) string {
+	return fmt.Sprintf("⚠️ 要約に失敗しました: %v", err)
+}
+
+// ConvertSlackMessagesToNotionCalloutBlocks convert slack messages of the thread to notion callout blocks
+func (a *Archiver) ConvertSlackMessagesToNotionCalloutBlocks(thread Thread) []notion.Block {
+	var children []notion.Block
+	for index, message := range thread.Messages {
+		var emoji string
+		if index == 0 {
+			emoji = "❓"
+		} else {
+			emoji = "📝"
+		}
+
+		children = append(children, a.ConvertSlackMessageToNotionCalloutBlock(thread, message, emoji))
+	}
+	return children
+}
+
+// ConvertSlackMessageToNotionCalloutBlock convert a slack message of the thread to a notion callout block with the emoji icon.
+// The callout starts with the author and the time linked to the message, followed by the leading paragraph.
+// Code blocks, quotes, lists and shared files are nested as its children.
+func (a *Archiver) ConvertSlackMessageToNotionCalloutBlock(thread Thread, message slack.Message, emoji string) notion.Block {
+	link := ReplyPermalink(thread.Permalink, thread.Channel, message.Timestamp, thread.Timestamp)
+	text := a.messageHeader(message, link)
+	children := a.parser.ParseBlocks(message.Text)
+	if len(children) != 0 && children[0].Type == notion.BlockTypeParagraph {
+		text = append(text, children[0].Paragraph.Text...)
+		children = children[1:]
+	}
+	// Notion accepts only two levels of nested blocks per request, and the callout is already the first
+	children = flattenListItems(children)
+	children = append(children, fileBlocks(message.Files, thread.FileURLs)...)
+
+	return notion.Block{
+		Object: "block",
+		Type:   notion.BlockTypeCallout,
+		Callout: &notion.Callout{
+			RichTextBlock: notion.RichTextBlock{
+				Text:     text,
+				Children: children,
+			},
+			Icon: &notion.Icon{
+				Type:  notion.IconTypeEmoji,
+				Emoji: &emoji,
+			},
+		},
+	}
+}
+
+// flattenListItems lifts nested list items next to their parent
+func flattenListItems(blocks []notion.Block) []notion.Block {
+	var flat []notion.Block
+	for _, b := range blocks {
+		var item *notion.RichTextBlock
+		switch b.Type {
+		case notion.BlockTypeBulletedListItem:
+			item = b.BulletedListItem
+		case notion.BlockTypeNumberedListItem:
+			item = b.NumberedListItem
+		}
+		if item == nil || len(item.Children) == 0 {
+			flat = append(flat, b)
+			continue
+		}
+
+		nested := item.Children
+		copied := *item
+		copied.Children = nil
+		if b.Type == notion.BlockTypeBulletedListItem {
+			b.BulletedListItem = &copied
+		} else {
+			b.NumberedListItem = &copied
+		}
+		flat = append(flat, b)
+		flat = append(flat, flattenListItems(nested)...)
+	}
+	return flat
+}
@@ -0,0 +1,286 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+)
+
+// DefaultTitleProperty is the title property used when the database schema is not loaded
+const DefaultTitleProperty = "Name"
+
+// ErrInvalidSchema is returned when a database lacks a configured property or its type does not match
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// compatibleTypes lists the property types each source can be written to
+var compatibleTypes = map[string][]notion.DatabasePropertyType{
+	config.SourceChannel:      {notion.DBPropTypeSelect, notion.DBPropTypeMultiSelect, notion.DBPropTypeRichText},
+	config.SourceParticipants: {notion.DBPropTypeMultiSelect, notion.DBPropTypePeople, notion.DBPropTypeRichText},
+	config.SourceStartedAt:    {notion.DBPropTypeDate},
+	config.SourcePermalink:    {notion.DBPropTypeURL, notion.DBPropTypeRichText},
+	config.SourceReplyCount:   {notion.DBPropTypeNumber},
+	config.SourceReactor:      {notion.DBPropTypePeople, notion.DBPropTypeSelect, notion.DBPropTypeMultiSelect, notion.DBPropTypeRichText},
+}
+
+// schemas caches the properties of the routed databases
+type schemas struct {
+	mu         sync.RWMutex
+	properties map[string]notion.DatabaseProperties
+}
+
+func (s *schemas) get(databaseID string) (notion.DatabaseProperties, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	props, ok := s.properties[databaseID]
+	return props, ok
+}
+
+func (s *schemas) delete(databaseID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.properties, databaseID)
+}
+
+func (s *schemas) set(databaseID string, props notion.DatabaseProperties) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.properties == nil {
+		s.properties = map[string]notion.DatabaseProperties{}
+	}
+	s.properties[databaseID] = props
+}
+
+// loadSchema fetches the schema of the route's database with FindDatabaseByID and validates the thread ID
+// property, the status property of the "status" reaction_removed action and the property mapping of the
+// route against it. The schema is only fetched on the first call, so call it before archiving to every
+// route: the property mapping is skipped without the schema.
+// A schema that fails validation is not cached, so the database is fetched again once it has been fixed.
+func (a *Archiver) loadSchema(ctx context.Context, route config.Route) error {
+	props, err := a.schema(ctx, route.Database)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	if p, ok := props[a.threadIDProperty()]; !ok || p.Type != notion.DBPropTypeRichText {
+		errs = append(errs, fmt.Sprintf("%q must be a rich_text property", a.threadIDProperty()))
+	}
+	if action := a.options.ReactionRemoved; action.Action == config.ReactionRemovedStatus {
+		if p, ok := props[action.StatusProperty]; !ok || p.Type != notion.DBPropTypeSelect {
+			errs = append(errs, fmt.Sprintf("%q must be a select property for reaction_removed", action.StatusProperty))
+		}
+	}
+	for name, source := range route.Properties {
+		p, ok := props[name]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("property %q does not exist", name))
+			continue
+		}
+		if !isCompatible(source, p.Type) {
+			errs = append(errs, fmt.Sprintf("property %q is %s, but %s needs one of %v", name, p.Type, source, compatibleTypes[source]))
+		}
+	}
+
+	if len(errs) != 0 {
+		// Fetch the schema again next time, so that fixing the database takes effect without a restart
+		a.schemas.delete(route.Database)
+		sort.Strings(errs)
+		return fmt.Errorf("%w of database %s: %s", ErrInvalidSchema, route.Database, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// modalProperty is a property written by the task modal and its expected type
+type modalProperty struct {
+	name string
+	typ  notion.DatabasePropertyType
+}
+
+// LoadModalSchemas fetches the schema of every database the task modal writes to and validates the
+// properties of modal against it: the title and the due date, the tags when modal.Tags is set, and the
+// assignee when Options.NotionUsers is set. Call it before creating every task: the schemas are only fetched on the first call,
+// except those that failed validation.
+func (a *Archiver) LoadModalSchemas(ctx context.Context, modal config.Modal, databases []string) error {
+	expected := []modalProperty{
+		{modal.TitleProperty, notion.DBPropTypeTitle},
+		{modal.DueProperty, notion.DBPropTypeDate},
+	}
+	if len(modal.Tags) > 0 {
+		expected = append(expected, modalProperty{modal.TagsProperty, notion.DBPropTypeMultiSelect})
+	}
+	if len(a.options.NotionUsers) > 0 {
+		expected = append(expected, modalProperty{modal.AssigneeProperty, notion.DBPropTypePeople})
+	}
+
+	var errs []string
+	for _, id := range databases {
+		props, err := a.schema(ctx, id)
+		if err != nil {
+			return err
+		}
+		valid := true
+		for _, e := range expected {
+			if p, ok := props[e.name]; !ok || p.Type != e.typ {
+				errs = append(errs, fmt.Sprintf("database %s: %q must be a %s property", id, e.name, e.typ))
+				valid = false
+			}
+		}
+		if !valid {
+			a.schemas.delete(id)
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("%w of the modal databases:\n  %s", ErrInvalidSchema, strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// schema returns the cached properties of the database, fetching them on first use
+func (a *Archiver) schema(ctx context.Context, databaseID string) (notion.DatabaseProperties, error) {
+	if props, ok := a.schemas.get(databaseID); ok {
+		return props, nil
+	}
+	db, err := a.notion.FindDatabaseByID(ctx, databaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find database %s: %w", databaseID, err)
+	}
+	a.schemas.set(databaseID, db.Properties)
+	return db.Properties, nil
+}
+
+func isCompatible(source string, typ notion.DatabasePropertyType) bool {
+	for _, t := range compatibleTypes[source] {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// titleProperty returns the name of the title property of the database
+func (a *Archiver) titleProperty(databaseID string) string {
+	props, _ := a.schemas.get(databaseID)
+	for name, p := range props {
+		if p.Type == notion.DBPropTypeTitle {
+			return name
+		}
+	}
+	return DefaultTitleProperty
+}
+
+// mappedProperties fills the properties of the route's mapping with the thread metadata.
+// Mappings whose property type is unknown (the schema is not loaded) are skipped.
+func (a *Archiver) mappedProperties(route config.Route, thread Thread) notion.DatabasePageProperties {
+	properties := notion.DatabasePageProperties{}
+	if len(route.Properties) == 0 {
+		return properties
+	}
+
+	schema, ok := a.schemas.get(route.Database)
+	if !ok {
+		log.Printf("[ERROR] schema of database %s is not loaded; skip property mapping", route.Database)
+		return properties
+	}
+
+	for name, source := range route.Properties {
+		typ := schema[name].Type
+		if !isCompatible(source, typ) {
+			continue
+		}
+		if prop, ok := a.propertyValue(source, typ, thread); ok {
+			properties[name] = prop
+		}
+	}
+	return properties
+}
+
+func (a *Archiver) propertyValue(source string, typ notion.DatabasePropertyType, thread Thread) (notion.DatabasePageProperty, bool) {
+	switch source {
+	case config.SourceChannel:
+		return a.namesProperty(typ, []string{a.directory.ChannelName(thread.Channel)}, nil), true
+	case config.SourceParticipants:
+		return a.namesProperty(typ, a.userNames(thread.Participants()), thread.Participants()), true
+	case config.SourceReactor:
+		if thread.Reactor == "" {
+			return notion.DatabasePageProperty{}, false
+		}
+		return a.namesProperty(typ, a.userNames([]string{thread.Reactor}), []string{thread.Reactor}), true
+	case config.SourceStartedAt:
+		t, err := ParseTimestamp(thread.Timestamp)
+		if err != nil {
+			return notion.DatabasePageProperty{}, false
+		}
+		if a.options.Location != nil {
+			t = t.In(a.options.Location)
+		}
+		return notion.DatabasePageProperty{Date: &notion.Date{Start: notion.NewDateTime(t, true)}}, true
+	case config.SourcePermalink:
+		if typ == notion.DBPropTypeRichText {
+			return notion.DatabasePageProperty{RichText: plainRichText(thread.Permalink)}, true
+		}
+		link := thread.Permalink
+		return notion.DatabasePageProperty{URL: &link}, true
+	case config.SourceReplyCount:
+		count := float64(len(thread.Messages) - 1)
+		return notion.DatabasePageProperty{Number: &count}, true
+	}
+	return notion.DatabasePageProperty{}, false
+}
+
+// namesProperty writes names to a select, multi_select or rich_text property, or the Notion
+// users mapped from slackUsers to a people property
+func (a *Archiver) namesProperty(typ notion.DatabasePropertyType, names []string, slackUsers []string) notion.DatabasePageProperty {
+	switch typ {
+	case notion.DBPropTypeSelect:
+		return notion.DatabasePageProperty{Select: &notion.SelectOptions{Name: selectOptionName(names[0])}}
+	case notion.DBPropTypeMultiSelect:
+		options := []notion.SelectOptions{}
+		for _, n := range names {
+			options = append(options, notion.SelectOptions{Name: selectOptionName(n)})
+		}
+		return notion.DatabasePageProperty{MultiSelect: options}
+	case notion.DBPropTypePeople:
+		people := []notion.User{}
+		for _, u := range slackUsers {
+			if id, ok := a.directory.NotionUserID(u); ok {
+				people = append(people, notion.User{ID: id})
+			}
+		}
+		return notion.DatabasePageProperty{People: people}
+	default:
+		return notion.DatabasePageProperty{RichText: plainRichText(strings.Join(names, ", "))}
+	}
+}
+
+func (a *Archiver) userNames(users []string) []string {
+	names := make([]string, 0, len(users))
+	for _, u := range users {
+		names = append(names, a.directory.UserName(u))
+	}
+	return names
+}
+
+// selectOptionName removes commas, which Notion does not allow in select options
+func selectOptionName(name string) string {
+	return TruncateText(strings.ReplaceAll(name, ",", " "), 100)
+}
+
+func plainRichText(content string) []notion.RichText {
+	return []notion.RichText{
+		{
+			Type: notion.RichTextTypeText,
+			Text: &notion.Text{Content: TruncateText(content, maxRichTextLength)},
+		},
+	}
+}
@@ -0,0 +1,214 @@
+package archive_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive/archivetest"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/slack-go/slack"
+)
+
+func TestLoadModalSchemas(t *testing.T) {
+	modal := config.Modal{TitleProperty: "Name", TagsProperty: "Tags", DueProperty: "Due", AssigneeProperty: "Assignee"}
+	tasks := notion.DatabaseProperties{
+		"Name":     {Type: notion.DBPropTypeTitle},
+		"Tags":     {Type: notion.DBPropTypeMultiSelect},
+		"Due":      {Type: notion.DBPropTypeDate},
+		"Assignee": {Type: notion.DBPropTypePeople},
+	}
+	notes := notion.DatabaseProperties{
+		"Name": {Type: notion.DBPropTypeTitle},
+		"Due":  {Type: notion.DBPropTypeRichText},
+	}
+
+	tests := []struct {
+		name        string
+		databases   []string
+		tags        []string
+		notionUsers map[string]string
+		// wantErr lists substrings of the error, or nothing when the schemas are valid
+		wantErr []string
+	}{
+		{name: "every field", databases: []string{"tasks"}, tags: []string{"bug"}, notionUsers: map[string]string{"a@example.com": "n1"}},
+		{name: "unused fields are not required", databases: []string{"tasks", "minimal"}},
+		{name: "wrong type", databases: []string{"notes"}, wantErr: []string{`database notes: "Due" must be a date property`}},
+		{name: "missing tags", databases: []string{"minimal"}, tags: []string{"bug"}, wantErr: []string{`"Tags" must be a multi_select property`}},
+		{name: "missing assignee", databases: []string{"minimal"}, notionUsers: map[string]string{"a@example.com": "n1"}, wantErr: []string{`"Assignee" must be a people property`}},
+		{name: "unknown database", databases: []string{"unknown"}, wantErr: []string{"failed to find database unknown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &archivetest.Notion{Databases: map[string]notion.Database{
+				"tasks":   {ID: "tasks", Properties: tasks},
+				"notes":   {ID: "notes", Properties: notes},
+				"minimal": {ID: "minimal", Properties: notion.DatabaseProperties{"Name": {Type: notion.DBPropTypeTitle}, "Due": {Type: notion.DBPropTypeDate}}},
+			}}
+			a := archive.New(&archivetest.Slack{}, n, &archivetest.OpenAI{}, archive.Options{NotionUsers: tt.notionUsers})
+			m := modal
+			m.Tags = tt.tags
+
+			err := a.LoadModalSchemas(context.Background(), m, tt.databases)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("err = nil, want %v", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("err = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+// describeProperty formats the value of a page property, e.g. "select:general" or "people:n1,n2"
+func describeProperty(p notion.DatabasePageProperty) string {
+	switch {
+	case p.Select != nil:
+		return "select:" + p.Select.Name
+	case p.MultiSelect != nil:
+		var names []string
+		for _, o := range p.MultiSelect {
+			names = append(names, o.Name)
+		}
+		return "multi_select:" + strings.Join(names, ",")
+	case p.People != nil:
+		var ids []string
+		for _, u := range p.People {
+			ids = append(ids, u.ID)
+		}
+		return "people:" + strings.Join(ids, ",")
+	case p.RichText != nil:
+		return "rich_text:" + p.RichText[0].Text.Content
+	case p.Date != nil:
+		return "date:" + p.Date.Start.Time.UTC().Format(time.RFC3339)
+	case p.URL != nil:
+		return "url:" + *p.URL
+	case p.Number != nil:
+		return fmt.Sprintf("number:%g", *p.Number)
+	}
+	return ""
+}
+
+func TestReactionAddedEventHandlerMapsProperties(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		typ    notion.DatabasePropertyType
+		want   string
+		// wantErr is a substring of the schema error, which stops archiving
+		wantErr string
+	}{
+		{name: "channel to select", source: config.SourceChannel, typ: notion.DBPropTypeSelect, want: "select:general"},
+		{name: "channel to rich_text", source: config.SourceChannel, typ: notion.DBPropTypeRichText, want: "rich_text:general"},
+		{name: "participants to multi_select", source: config.SourceParticipants, typ: notion.DBPropTypeMultiSelect, want: "multi_select:alice,bob"},
+		{name: "participants to people", source: config.SourceParticipants, typ: notion.DBPropTypePeople, want: "people:notion-alice"},
+		{name: "participants to rich_text", source: config.SourceParticipants, typ: notion.DBPropTypeRichText, want: "rich_text:alice, bob"},
+		{name: "reactor to people", source: config.SourceReactor, typ: notion.DBPropTypePeople, want: "people:notion-carol"},
+		{name: "reactor to select", source: config.SourceReactor, typ: notion.DBPropTypeSelect, want: "select:carol"},
+		{name: "started_at to date", source: config.SourceStartedAt, typ: notion.DBPropTypeDate, want: "date:2023-03-28T10:40:00Z"},
+		{name: "permalink to url", source: config.SourcePermalink, typ: notion.DBPropTypeURL, want: "url:https://example.slack.com/archives/C1/p" + parentTS},
+		{name: "reply_count to number", source: config.SourceReplyCount, typ: notion.DBPropTypeNumber, want: "number:2"},
+		{name: "incompatible type", source: config.SourceReplyCount, typ: notion.DBPropTypeRichText, wantErr: `property "Mapped" is rich_text, but reply_count needs one of [number]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &archivetest.Slack{
+				Users: map[string]*slack.User{
+					"U1": {ID: "U1", Profile: slack.UserProfile{DisplayName: "alice", Email: "alice@example.com"}},
+					"U2": {ID: "U2", Profile: slack.UserProfile{DisplayName: "bob"}},
+					"U9": {ID: "U9", Profile: slack.UserProfile{DisplayName: "carol", Email: "carol@example.com"}},
+				},
+				Channels: map[string]*slack.Channel{
+					channel: {GroupConversation: slack.GroupConversation{Name: "general"}},
+				},
+			}
+			s.AddThread(channel, newThread(3)...)
+			n := &archivetest.Notion{Databases: map[string]notion.Database{
+				database: {ID: database, Properties: notion.DatabaseProperties{
+					"Name":                          {Type: notion.DBPropTypeTitle},
+					archive.DefaultThreadIDProperty: {Type: notion.DBPropTypeRichText},
+					"Mapped":                        {Type: tt.typ},
+				}},
+			}}
+			a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{
+				Routes:      []config.Route{{Reaction: reaction, Database: database, Properties: config.PropertyMapping{"Mapped": tt.source}}},
+				NotionUsers: map[string]string{"alice@example.com": "notion-alice", "carol@example.com": "notion-carol"},
+			})
+
+			err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if len(n.Created) != 0 {
+					t.Fatalf("created %d pages, want none", len(n.Created))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describeProperty(onlyPage(t, n).Properties["Mapped"]); got != tt.want {
+				t.Errorf("Mapped = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReactionAddedEventHandlerRefetchesInvalidSchema(t *testing.T) {
+	s := &archivetest.Slack{}
+	s.AddThread(channel, newThread(1)...)
+	n := &archivetest.Notion{Databases: map[string]notion.Database{
+		database: {ID: database, Properties: notion.DatabaseProperties{"Name": {Type: notion.DBPropTypeTitle}}},
+	}}
+	a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{Routes: routes})
+
+	err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction))
+	if !errors.Is(err, archive.ErrInvalidSchema) {
+		t.Fatalf("err = %v, want %v", err, archive.ErrInvalidSchema)
+	}
+
+	// The thread ID property is added to the database
+	n.Databases[database] = newNotion().Databases[database]
+	if err := a.ReactionAddedEventHandler(context.Background(), reactionAdded(reaction)); err != nil {
+		t.Fatalf("err = %v after fixing the database, want nil", err)
+	}
+	if len(n.Created) != 1 {
+		t.Errorf("created %d pages, want 1", len(n.Created))
+	}
+}
+
+func TestLoadModalSchemasRefetchesInvalidSchema(t *testing.T) {
+	modal := config.Modal{TitleProperty: "Name", DueProperty: "Due"}
+	n := &archivetest.Notion{Databases: map[string]notion.Database{
+		"tasks": {ID: "tasks", Properties: notion.DatabaseProperties{"Name": {Type: notion.DBPropTypeTitle}}},
+	}}
+	a := archive.New(&archivetest.Slack{}, n, &archivetest.OpenAI{}, archive.Options{})
+
+	if err := a.LoadModalSchemas(context.Background(), modal, []string{"tasks"}); !errors.Is(err, archive.ErrInvalidSchema) {
+		t.Fatalf("err = %v, want %v", err, archive.ErrInvalidSchema)
+	}
+
+	n.Databases["tasks"] = notion.Database{ID: "tasks", Properties: notion.DatabaseProperties{
+		"Name": {Type: notion.DBPropTypeTitle},
+		"Due":  {Type: notion.DBPropTypeDate},
+	}}
+	if err := a.LoadModalSchemas(context.Background(), modal, []string{"tasks"}); err != nil {
+		t.Fatalf("err = %v after fixing the database, want nil", err)
+	}
+}
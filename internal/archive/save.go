@@ -0,0 +1,109 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+)
+
+// Preview is the generated content shown in the "Save to Notion" modal before the page is created
+type Preview struct {
+	Route   config.Route
+	Title   string
+	Summary string
+}
+
+// SaveRequest archives a thread with the values reviewed in the "Save to Notion" modal
+type SaveRequest struct {
+	Channel string `json:"channel"`
+	// ThreadTimestamp is the timestamp of the parent message, or of the message itself when it has no replies
+	ThreadTimestamp string `json:"thread_ts"`
+	// User is who saved the thread
+	User     string `json:"user"`
+	Database string `json:"database"`
+	Title    string `json:"title"`
+	Summary  string `json:"summary"`
+}
+
+// RouteForDatabase returns the first route of database that matches the channel, or the first route of the
+// channel when database is empty. Databases without a route are rejected since their schema is not loaded
+func (a *Archiver) RouteForDatabase(database string, channel string) (config.Route, bool) {
+	for _, r := range a.options.Routes {
+		if r.MatchesChannel(channel) && (database == "" || r.Database == database) {
+			return r, true
+		}
+	}
+	return config.Route{}, false
+}
+
+// Preview generates the title and summary of the thread without creating a page.
+// A message without replies is previewed as a thread of one message
+func (a *Archiver) Preview(ctx context.Context, channel string, threadTimestamp string) (Preview, error) {
+	route, ok := a.RouteForDatabase("", channel)
+	if !ok {
+		return Preview{}, fmt.Errorf("no route for channel %s", channel)
+	}
+	if err := a.loadSchema(ctx, route); err != nil {
+		return Preview{}, err
+	}
+
+	thread, ok, err := a.fetchThread(channel, threadTimestamp, "", true)
+	if err != nil {
+		return Preview{}, err
+	}
+	if !ok {
+		return Preview{}, fmt.Errorf("thread %s not found", ThreadKey(channel, threadTimestamp))
+	}
+
+	title, err := a.TitleStrategy(route).Title(ctx, thread)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	return Preview{
+		Route:   route,
+		Title:   title,
+		Summary: a.summarize(ctx, route, thread),
+	}, nil
+}
+
+// Save archives the thread of req with its reviewed title and summary
+func (a *Archiver) Save(ctx context.Context, req SaveRequest) (notion.Page, error) {
+	route, ok := a.RouteForDatabase(req.Database, req.Channel)
+	if !ok {
+		return notion.Page{}, fmt.Errorf("no route to database %s for channel %s", req.Database, req.Channel)
+	}
+	log.Printf("[INFO] route: reaction=%s database=%s", route.Reaction, route.Database)
+
+	if err := a.loadSchema(ctx, route); err != nil {
+		a.Notify(ctx, req.Channel, req.ThreadTimestamp, req.User, notion.Page{}, err)
+		return notion.Page{}, err
+	}
+
+	thread, ok, err := a.fetchThread(req.Channel, req.ThreadTimestamp, req.User, true)
+	if err == nil && !ok {
+		err = fmt.Errorf("thread %s not found", ThreadKey(req.Channel, req.ThreadTimestamp))
+	}
+	if err != nil {
+		a.Notify(ctx, req.Channel, req.ThreadTimestamp, req.User, notion.Page{}, err)
+		return notion.Page{}, err
+	}
+
+	page, found, err := a.UpdateArchivedPage(ctx, route, thread, req.Title)
+	if err == nil && !found {
+		thread.FileURLs = a.CopyFiles(ctx, thread.Messages)
+		digest := Digest{Title: req.Title, Summary: req.Summary}
+		a.extract(ctx, route, thread, &digest)
+
+		page, err = a.AddPageToNotionDB(ctx, route, thread, digest)
+	}
+	a.Notify(ctx, thread.Channel, thread.Timestamp, thread.Reactor, page, err)
+	if err != nil {
+		return notion.Page{}, err
+	}
+	log.Printf("[INFO] saved thread %s to page %s", thread.Key(), page.ID)
+	return page, nil
+}
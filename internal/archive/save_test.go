@@ -0,0 +1,151 @@
+package archive_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive/archivetest"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/summary"
+	"github.com/slack-go/slack"
+)
+
+func TestRouteForDatabase(t *testing.T) {
+	a := archive.New(&archivetest.Slack{}, &archivetest.Notion{}, &archivetest.OpenAI{}, archive.Options{
+		Routes: []config.Route{
+			{Reaction: "bug", Database: "db-1", Channels: []string{"C1"}},
+			{Reaction: "memo", Database: "db-2"},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		database string
+		channel  string
+		want     string
+		wantOK   bool
+	}{
+		{name: "first route of the channel", channel: "C1", want: "db-1", wantOK: true},
+		{name: "routed database", database: "db-2", channel: "C1", want: "db-2", wantOK: true},
+		{name: "database routed from another channel", database: "db-1", channel: "C2"},
+		{name: "unrouted database", database: "db-3", channel: "C1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, ok := a.RouteForDatabase(tt.database, tt.channel)
+			if ok != tt.wantOK || route.Database != tt.want {
+				t.Errorf("RouteForDatabase(%q, %q) = %q, %v, want %q, %v", tt.database, tt.channel, route.Database, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPreviewAndSave(t *testing.T) {
+	s := &archivetest.Slack{}
+	s.AddThread(channel, newThread(3)...)
+	n := newNotion()
+	n.Databases["db-2"] = n.Databases[database]
+	summarizer := &countingSummarizer{}
+	a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{
+		Routes: []config.Route{
+			{Reaction: reaction, Database: database, Summary: "counting"},
+			{Reaction: "bug", Database: "db-2", Channels: []string{"C2"}},
+		},
+		Summarizers: map[string]summary.Summarizer{"counting": summarizer},
+	})
+	ctx := context.Background()
+
+	preview, err := a.Preview(ctx, channel, parentTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Route.Database != database || preview.Title != "message 0" || preview.Summary != "summary" {
+		t.Errorf("Preview = %+v, want the title and summary generated for %s", preview, database)
+	}
+
+	save := archive.SaveRequest{
+		Channel:         channel,
+		ThreadTimestamp: parentTS,
+		User:            "U9",
+		Database:        database,
+		Title:           "edited title",
+		Summary:         "edited summary",
+	}
+
+	for _, db := range []string{"db-2", "db-3"} {
+		unrouted := save
+		unrouted.Database = db
+		if _, err := a.Save(ctx, unrouted); err == nil {
+			t.Errorf("Save to %s: err = nil, want a no route error", db)
+		}
+	}
+	if len(n.Created) != 0 {
+		t.Fatalf("created %d pages to databases not routed from the channel, want 0", len(n.Created))
+	}
+
+	if _, err := a.Save(ctx, save); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Created) != 1 {
+		t.Fatalf("created %d pages, want 1", len(n.Created))
+	}
+	created := n.Created[0]
+	if created.ParentID != database {
+		t.Errorf("parent = %s, want %s", created.ParentID, database)
+	}
+	if got := created.Title[0].Text.Content; got != "edited title" {
+		t.Errorf("title = %q, want %q", got, "edited title")
+	}
+	if got := plainText(created.Children[0]); !strings.HasSuffix(got, "■要約\n\nedited summary") {
+		t.Errorf("summary callout = %q, want the edited summary", got)
+	}
+	if got := created.Children[0].Type; got != notion.BlockTypeCallout {
+		t.Errorf("children[0] is %s, want callout", got)
+	}
+	// The reviewed summary is saved as it is, without summarizing the thread again
+	if summarizer.calls != 1 {
+		t.Errorf("summarized %d times, want 1", summarizer.calls)
+	}
+}
+
+func TestSaveStandaloneMessage(t *testing.T) {
+	s := &archivetest.Slack{}
+	s.AddThread(channel, slack.Message{Msg: slack.Msg{User: "U1", Text: "standalone message", Timestamp: parentTS}})
+	n := newNotion()
+	a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{Routes: routes})
+	ctx := context.Background()
+
+	preview, err := a.Preview(ctx, channel, parentTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Title != "standalone message" {
+		t.Errorf("Title = %q, want %q", preview.Title, "standalone message")
+	}
+
+	if _, err := a.Save(ctx, archive.SaveRequest{
+		Channel:         channel,
+		ThreadTimestamp: parentTS,
+		User:            "U9",
+		Database:        database,
+		Title:           preview.Title,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	page := onlyPage(t, n)
+	if got := richText(page.Properties[archive.DefaultThreadIDProperty].RichText); got != archive.ThreadKey(channel, parentTS) {
+		t.Errorf("thread ID = %q, want %q", got, archive.ThreadKey(channel, parentTS))
+	}
+
+	// A reaction still archives only the parent message of a thread
+	if err := a.ReactionAddedEventHandler(ctx, reactionAdded(reaction)); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Created) != 1 {
+		t.Errorf("created pages = %d, want 1", len(n.Created))
+	}
+}
@@ -0,0 +1,69 @@
+package archive
+
+import (
+	"github.com/slack-go/slack"
+)
+
+// GetThreadMessages gets all messages in the thread whose parent message is ts.
+// It returns no messages when ts is not the parent message of a thread
+func (a *Archiver) GetThreadMessages(channel string, ts string) ([]slack.Message, error) {
+	var messages []slack.Message
+	var cursor string
+	for {
+		params := &slack.GetConversationRepliesParameters{
+			ChannelID: channel,
+			Timestamp: ts,
+			Limit:     1000,
+			Cursor:    cursor,
+		}
+		threadMessages, hasMore, nextCursor, err := a.slack.GetConversationReplies(params)
+		if err != nil {
+			return messages, err
+		}
+
+		if len(threadMessages) == 0 || threadMessages[0].Timestamp != threadMessages[0].ThreadTimestamp {
+			break
+		}
+
+		// Slack returns the parent message at the head of every page
+		if cursor != "" {
+			threadMessages = threadMessages[1:]
+		}
+		messages = append(messages, threadMessages...)
+
+		if !hasMore {
+			break
+		}
+		cursor = nextCursor
+	}
+	return messages, nil
+}
+
+// GetMessage gets the message ts that is not in a thread.
+// It returns no messages when ts is the parent or a reply of a thread, or does not exist
+func (a *Archiver) GetMessage(channel string, ts string) ([]slack.Message, error) {
+	messages, _, _, err := a.slack.GetConversationReplies(&slack.GetConversationRepliesParameters{
+		ChannelID: channel,
+		Timestamp: ts,
+		Limit:     1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 || messages[0].Timestamp != ts || messages[0].ThreadTimestamp != "" {
+		return nil, nil
+	}
+	return messages[:1], nil
+}
+
+// GetMessagePermalink returns the permalink of the message
+func (a *Archiver) GetMessagePermalink(channel string, timestamp string) (string, error) {
+	permalink, err := a.slack.GetPermalink(&slack.PermalinkParameters{
+		Channel: channel,
+		Ts:      timestamp,
+	})
+	if err != nil {
+		return "", err
+	}
+	return permalink, nil
+}
@@ -0,0 +1,176 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/mrkdwn"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/summary"
+)
+
+// Digest is the generated content of the page
+type Digest struct {
+	// Title replaces the title generated by the TitleStrategy of the route when not empty
+	Title string
+	// Summary is shown in the ■要約 section, which is omitted when empty
+	Summary string
+	// Extraction is rendered as decisions, action items and open questions. nil omits the sections
+	Extraction *summary.Extraction
+	// ExtractionErr is shown instead of the sections when the extraction failed
+	ExtractionErr error
+}
+
+// Digest summarizes the thread, and extracts decisions, action items and open questions when the route enables it.
+// Failures are recorded in the Digest so that the thread is archived anyway
+func (a *Archiver) Digest(ctx context.Context, route config.Route, thread Thread) Digest {
+	digest := Digest{Summary: a.summarize(ctx, route, thread)}
+	a.extract(ctx, route, thread, &digest)
+	return digest
+}
+
+// summarize returns the summary of the thread, or the error message when the summarization failed
+func (a *Archiver) summarize(ctx context.Context, route config.Route, thread Thread) string {
+	// 要約は無料枠を超えると課金が発生するので、ルートかチャンネルで設定された場合のみ行う
+	text, err := a.Summarizer(route, thread.Channel).Summarize(ctx, a.summaryThread(thread), a.Prompt(route))
+	if err != nil {
+		// 要約に失敗してもアーカイブは止めず、失敗したことをページに残す
+		log.Printf("[ERROR] Failed to summarize thread: %v", err)
+		return summaryFailedText(err)
+	}
+	return text
+}
+
+// extract sets the extraction of the thread to digest when the route enables it
+func (a *Archiver) extract(ctx context.Context, route config.Route, thread Thread, digest *Digest) {
+	extractor, ok := a.Summarizer(route, thread.Channel).(summary.Extractor)
+	if route.Extract == nil || !*route.Extract || !ok {
+		return
+	}
+	extraction, err := extractor.Extract(ctx, a.summaryThread(thread), a.Prompt(route))
+	if err != nil {
+		log.Printf("[ERROR] Failed to extract action items: %v", err)
+		digest.ExtractionErr = err
+		return
+	}
+	digest.Extraction = &extraction
+}
+
+// Summarizer returns the summarizer configured for the channel, or for the route.
+// Unknown names fall back to summary.Noop
+func (a *Archiver) Summarizer(route config.Route, channel string) summary.Summarizer {
+	name, ok := a.options.ChannelSummaries[channel]
+	if !ok {
+		name = route.Summary
+	}
+	if s, ok := a.options.Summarizers[name]; ok {
+		return s
+	}
+	return summary.Noop{}
+}
+
+// Prompt returns the summary prompt configured for the route
+func (a *Archiver) Prompt(route config.Route) config.Prompt {
+	if route.Prompt != nil {
+		return *route.Prompt
+	}
+	return config.Prompt{
+		System:       config.DefaultPromptSystem,
+		Instructions: config.DefaultPromptInstructions,
+		Language:     config.DefaultPromptLanguage,
+		Length:       config.DefaultPromptLength,
+	}
+}
+
+// summaryThread converts thread to the input of the summarizers, with mrkdwn and mentions rendered as plain text
+func (a *Archiver) summaryThread(thread Thread) summary.Thread {
+	clean := CleanTitle{Parser: &mrkdwn.Parser{Mentions: textMentions{a.directory}}}
+
+	participants := make([]string, 0, len(thread.Participants()))
+	for _, user := range thread.Participants() {
+		participants = append(participants, a.directory.UserName(user))
+	}
+
+	messages := make([]summary.Message, 0, len(thread.Messages))
+	for _, m := range thread.Messages {
+		messages = append(messages, summary.Message{
+			UserID: m.User,
+			Author: a.authorName(m),
+			Time:   a.formatTimestamp(m.Timestamp),
+			Text:   clean.plainText(m.Text),
+		})
+	}
+
+	return summary.Thread{
+		Channel:      a.directory.ChannelName(thread.Channel),
+		Participants: participants,
+		Messages:     messages,
+	}
+}
+
+// extractionBlocks renders the extraction of digest as headed sections: decisions and open questions
+// as bulleted lists, and action items as to-dos with the assignee mentioned
+func (a *Archiver) extractionBlocks(digest Digest) []notion.Block {
+	if digest.ExtractionErr != nil {
+		return []notion.Block{{
+			Object: "block",
+			Type:   notion.BlockTypeParagraph,
+			Paragraph: &notion.RichTextBlock{
+				Text: plainRichText(fmt.Sprintf("⚠️ アクションアイテムの抽出に失敗しました: %v", digest.ExtractionErr)),
+			},
+		}}
+	}
+	if digest.Extraction == nil || digest.Extraction.Empty() {
+		return nil
+	}
+	e := digest.Extraction
+
+	var blocks []notion.Block
+	if len(e.Decisions) > 0 {
+		blocks = append(blocks, headingBlock("決定事項"))
+		for _, d := range e.Decisions {
+			blocks = append(blocks, bulletedBlock(a.parser.Parse(d)))
+		}
+	}
+	if len(e.ActionItems) > 0 {
+		blocks = append(blocks, headingBlock("アクションアイテム"))
+		for _, item := range e.ActionItems {
+			text := a.parser.Parse(item.Task)
+			if item.Assignee != "" {
+				text = append(text, notion.RichText{Type: notion.RichTextTypeText, Text: &notion.Text{Content: " → "}})
+				text = append(text, a.parser.Parse(item.Assignee)...)
+			}
+			checked := false
+			blocks = append(blocks, notion.Block{
+				Object: "block",
+				Type:   notion.BlockTypeToDo,
+				ToDo:   &notion.ToDo{RichTextBlock: notion.RichTextBlock{Text: text}, Checked: &checked},
+			})
+		}
+	}
+	if len(e.OpenQuestions) > 0 {
+		blocks = append(blocks, headingBlock("未解決の質問"))
+		for _, q := range e.OpenQuestions {
+			blocks = append(blocks, bulletedBlock(a.parser.Parse(q)))
+		}
+	}
+	return blocks
+}
+
+func headingBlock(text string) notion.Block {
+	return notion.Block{
+		Object:   "block",
+		Type:     notion.BlockTypeHeading2,
+		Heading2: &notion.Heading{Text: plainRichText(text)},
+	}
+}
+
+func bulletedBlock(text []notion.RichText) notion.Block {
+	return notion.Block{
+		Object:           "block",
+		Type:             notion.BlockTypeBulletedListItem,
+		BulletedListItem: &notion.RichTextBlock{Text: text},
+	}
+}
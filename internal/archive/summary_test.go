@@ -0,0 +1,120 @@
+package archive_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive/archivetest"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/summary"
+	"github.com/slack-go/slack"
+)
+
+// describeBlock renders the type and the text of an extraction block, with to-dos as "to_do[x]" or "to_do[ ]"
+func describeBlock(b notion.Block) string {
+	switch b.Type {
+	case notion.BlockTypeHeading2:
+		return "heading: " + richText(b.Heading2.Text)
+	case notion.BlockTypeBulletedListItem:
+		return "bullet: " + richText(b.BulletedListItem.Text)
+	case notion.BlockTypeToDo:
+		mark := "[ ]"
+		if b.ToDo.Checked == nil {
+			mark = "[?]"
+		} else if *b.ToDo.Checked {
+			mark = "[x]"
+		}
+		return fmt.Sprintf("to_do%s: %s", mark, richText(b.ToDo.Text))
+	case notion.BlockTypeParagraph:
+		return "paragraph: " + richText(b.Paragraph.Text)
+	}
+	return string(b.Type)
+}
+
+func TestAddPageToNotionDBRendersExtraction(t *testing.T) {
+	tests := []struct {
+		name   string
+		digest archive.Digest
+		// want describes the blocks between the summary callout and the messages
+		want []string
+	}{
+		{
+			name: "every section",
+			digest: archive.Digest{Extraction: &summary.Extraction{
+				Decisions: []string{"ship on *Friday*"},
+				ActionItems: []summary.ActionItem{
+					{Task: "write the release note", Assignee: "<@U1>"},
+					{Task: "review it", Assignee: "<@U2>"},
+					{Task: "book a room"},
+				},
+				OpenQuestions: []string{"who announces it?"},
+			}},
+			want: []string{
+				"heading: 決定事項",
+				"bullet: ship on Friday",
+				"heading: アクションアイテム",
+				"to_do[ ]: write the release note → <person:notion-alice>",
+				"to_do[ ]: review it → @bob",
+				"to_do[ ]: book a room",
+				"heading: 未解決の質問",
+				"bullet: who announces it?",
+			},
+		},
+		{
+			name:   "only action items",
+			digest: archive.Digest{Extraction: &summary.Extraction{ActionItems: []summary.ActionItem{{Task: "fix the bug", Assignee: "carol"}}}},
+			want: []string{
+				"heading: アクションアイテム",
+				"to_do[ ]: fix the bug → carol",
+			},
+		},
+		{
+			name:   "empty extraction",
+			digest: archive.Digest{Extraction: &summary.Extraction{}},
+		},
+		{
+			name:   "extraction disabled",
+			digest: archive.Digest{},
+		},
+		{
+			name:   "extraction error",
+			digest: archive.Digest{ExtractionErr: errors.New("invalid JSON in the answer")},
+			want:   []string{"paragraph: ⚠️ アクションアイテムの抽出に失敗しました: invalid JSON in the answer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &archivetest.Slack{
+				Users: map[string]*slack.User{
+					"U1": {ID: "U1", Profile: slack.UserProfile{DisplayName: "alice", Email: "alice@example.com"}},
+					"U2": {ID: "U2", Profile: slack.UserProfile{DisplayName: "bob"}},
+				},
+			}
+			n := newNotion()
+			a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{
+				Routes:      routes,
+				NotionUsers: map[string]string{"alice@example.com": "notion-alice"},
+			})
+			thread := archive.Thread{Channel: channel, Timestamp: parentTS, Messages: newThread(1)}
+
+			if _, err := a.AddPageToNotionDB(context.Background(), routes[0], thread, tt.digest); err != nil {
+				t.Fatal(err)
+			}
+
+			children := onlyPage(t, n).Children
+			// The summary callout comes first and the callout of the only message last
+			var got []string
+			for _, b := range children[1 : len(children)-1] {
+				got = append(got, describeBlock(b))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blocks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
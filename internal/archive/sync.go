@@ -0,0 +1,108 @@
+package archive
+
+import (
+	"context"
+	"log"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// mirroredSubTypes are the message subtypes of thread replies that are appended to archived pages
+var mirroredSubTypes = map[string]bool{
+	"":                 true,
+	"thread_broadcast": true,
+	"file_share":       true,
+}
+
+// MessageEventHandler appends a new thread reply to the Notion page of the thread, if it has been archived.
+// The Slack app needs the message.channels (and message.groups) event subscriptions.
+func (a *Archiver) MessageEventHandler(ctx context.Context, event *slackevents.MessageEvent) error {
+	if !IsMirroredReply(event) {
+		return nil
+	}
+
+	key := ThreadKey(event.Channel, event.ThreadTimeStamp)
+	page, found, err := a.FindArchivedPage(ctx, event.Channel, key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	message := slack.Message{Msg: slack.Msg{
+		Channel:         event.Channel,
+		User:            event.User,
+		Text:            event.Text,
+		Timestamp:       event.TimeStamp,
+		ThreadTimestamp: event.ThreadTimeStamp,
+		Files:           convertEventFiles(event.Files),
+	}}
+
+	link, err := a.GetMessagePermalink(event.Channel, event.ThreadTimeStamp)
+	if err != nil {
+		return err
+	}
+	thread := Thread{
+		Channel:   event.Channel,
+		Timestamp: event.ThreadTimeStamp,
+		Permalink: link,
+		Messages:  []slack.Message{message},
+		FileURLs:  a.CopyFiles(ctx, []slack.Message{message}),
+	}
+
+	log.Printf("[INFO] Start AppendBlockChildren: thread=%s page=%s", key, page.ID)
+	return a.AppendBlocks(ctx, page.ID, []notion.Block{
+		a.ConvertSlackMessageToNotionCalloutBlock(thread, message, "📝"),
+	})
+}
+
+// IsMirroredReply reports whether the message event is a thread reply that MessageEventHandler appends.
+// Top-level messages, edits, deletions and replies of bots, including our own notifications, are not
+func IsMirroredReply(event *slackevents.MessageEvent) bool {
+	if event.ThreadTimeStamp == "" || event.ThreadTimeStamp == event.TimeStamp {
+		return false
+	}
+	return mirroredSubTypes[event.SubType] && event.BotID == ""
+}
+
+// FindArchivedPage looks for the page of the thread in every database routed from the channel
+func (a *Archiver) FindArchivedPage(ctx context.Context, channel string, threadKey string) (notion.Page, bool, error) {
+	searched := map[string]bool{}
+	for _, route := range a.options.Routes {
+		if searched[route.Database] || !route.MatchesChannel(channel) {
+			continue
+		}
+		searched[route.Database] = true
+
+		page, found, err := a.FindPageByThread(ctx, route.Database, threadKey)
+		if err != nil {
+			return notion.Page{}, false, err
+		}
+		if found {
+			return page, true, nil
+		}
+	}
+	return notion.Page{}, false, nil
+}
+
+func convertEventFiles(files []slackevents.File) []slack.File {
+	var converted []slack.File
+	for _, f := range files {
+		converted = append(converted, slack.File{
+			ID:                 f.ID,
+			Name:               f.Name,
+			Title:              f.Title,
+			Mimetype:           f.Mimetype,
+			Filetype:           f.Filetype,
+			Mode:               f.Mode,
+			Size:               f.Size,
+			URLPrivate:         f.URLPrivate,
+			URLPrivateDownload: f.URLPrivateDownload,
+			Permalink:          f.Permalink,
+		})
+	}
+	return converted
+}
@@ -0,0 +1,96 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+)
+
+// ErrUnmappedAssignee is returned when the assignee of the task is not in Options.NotionUsers
+var ErrUnmappedAssignee = errors.New("assignee is not in notion_users")
+
+// Task is a task added from the task modal. It has the fields of modal.Task, which converts to it
+type Task struct {
+	Title    string
+	Content  string
+	Database string
+	Tags     []string
+	// DueDate is "YYYY-MM-DD", or empty
+	DueDate string
+	// Assignee is the Slack user ID, or empty
+	Assignee string
+}
+
+// AddTask creates the page of the task in task.Database, writing its answers to the properties of modal.
+// Call LoadModalSchemas first to check the properties exist
+func (a *Archiver) AddTask(ctx context.Context, modal config.Modal, task Task) (notion.Page, error) {
+	properties, err := a.taskProperties(modal, task)
+	if err != nil {
+		return notion.Page{}, err
+	}
+	title := plainRichText(task.Title)
+	properties[modal.TitleProperty] = notion.DatabasePageProperty{Title: title}
+
+	// The content is not truncated like the properties, since NormalizeBlocks splits long text
+	content := []notion.RichText{
+		{
+			Type: notion.RichTextTypeText,
+			Text: &notion.Text{Content: task.Content},
+		},
+	}
+	children := NormalizeBlocks([]notion.Block{
+		{
+			Object:    "block",
+			Type:      notion.BlockTypeParagraph,
+			Paragraph: &notion.RichTextBlock{Text: content},
+		},
+	})
+
+	return a.notion.CreatePage(ctx, notion.CreatePageParams{
+		ParentID:               task.Database,
+		ParentType:             notion.ParentTypeDatabase,
+		Title:                  title,
+		DatabasePageProperties: &properties,
+		Children:               children,
+	})
+}
+
+// taskProperties maps the tags, due date and assignee of the task to the Notion properties of modal.
+// It returns ErrUnmappedAssignee when the assignee has no Notion user
+func (a *Archiver) taskProperties(modal config.Modal, task Task) (notion.DatabasePageProperties, error) {
+	properties := notion.DatabasePageProperties{}
+
+	if len(task.Tags) > 0 {
+		var options []notion.SelectOptions
+		for _, tag := range task.Tags {
+			options = append(options, notion.SelectOptions{Name: tag})
+		}
+		properties[modal.TagsProperty] = notion.DatabasePageProperty{MultiSelect: options}
+	}
+
+	if task.DueDate != "" {
+		due, err := time.Parse("2006-01-02", task.DueDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid due date %q: %w", task.DueDate, err)
+		}
+		properties[modal.DueProperty] = notion.DatabasePageProperty{
+			Date: &notion.Date{Start: notion.NewDateTime(due, false)},
+		}
+	}
+
+	if task.Assignee != "" {
+		notionUserID, ok := a.directory.NotionUserID(task.Assignee)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnmappedAssignee, task.Assignee)
+		}
+		properties[modal.AssigneeProperty] = notion.DatabasePageProperty{
+			People: []notion.User{{ID: notionUserID}},
+		}
+	}
+
+	return properties, nil
+}
@@ -0,0 +1,125 @@
+package archive_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive/archivetest"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/slack-go/slack"
+)
+
+func TestAddTask(t *testing.T) {
+	modal := config.Modal{TitleProperty: "Name", TagsProperty: "Tags", DueProperty: "Due", AssigneeProperty: "Assignee"}
+
+	tests := []struct {
+		name string
+		task archive.Task
+		// want are the properties of the page besides the title
+		want notion.DatabasePageProperties
+		// wantErr is a substring of the error, or empty when the page is created
+		wantErr string
+	}{
+		{
+			name: "title only",
+			task: archive.Task{Title: "task", Database: database},
+			want: notion.DatabasePageProperties{},
+		},
+		{
+			name: "tags, due date and mapped assignee",
+			task: archive.Task{Title: "task", Database: database, Tags: []string{"bug", "urgent"}, DueDate: "2023-04-01", Assignee: "U1"},
+			want: notion.DatabasePageProperties{
+				"Tags":     {MultiSelect: []notion.SelectOptions{{Name: "bug"}, {Name: "urgent"}}},
+				"Due":      {Date: &notion.Date{Start: notion.NewDateTime(time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC), false)}},
+				"Assignee": {People: []notion.User{{ID: "notion-alice"}}},
+			},
+		},
+		{
+			name:    "unmapped assignee",
+			task:    archive.Task{Title: "task", Database: database, Assignee: "U2"},
+			wantErr: archive.ErrUnmappedAssignee.Error() + ": U2",
+		},
+		{
+			name:    "invalid due date",
+			task:    archive.Task{Title: "task", Database: database, DueDate: "2023/04/01"},
+			wantErr: `invalid due date "2023/04/01"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &archivetest.Slack{Users: map[string]*slack.User{
+				"U1": {ID: "U1", Profile: slack.UserProfile{Email: "alice@example.com"}},
+				"U2": {ID: "U2", Profile: slack.UserProfile{Email: "bob@example.com"}},
+			}}
+			n := &archivetest.Notion{}
+			a := archive.New(s, n, &archivetest.OpenAI{}, archive.Options{
+				NotionUsers: map[string]string{"alice@example.com": "notion-alice"},
+			})
+
+			_, err := a.AddTask(context.Background(), modal, tt.task)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if len(n.Created) != 0 {
+					t.Errorf("created %d pages, want 0", len(n.Created))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(n.Created) != 1 {
+				t.Fatalf("created %d pages, want 1", len(n.Created))
+			}
+			created := n.Created[0]
+			if created.ParentID != database {
+				t.Errorf("parent = %s, want %s", created.ParentID, database)
+			}
+			properties := *created.DatabasePageProperties
+			if got := richText(properties["Name"].Title); got != tt.task.Title {
+				t.Errorf("title = %q, want %q", got, tt.task.Title)
+			}
+			delete(properties, "Name")
+			if len(properties) != len(tt.want) {
+				t.Fatalf("properties = %v, want %v", properties, tt.want)
+			}
+			for name, want := range tt.want {
+				got := properties[name]
+				switch {
+				case want.MultiSelect != nil:
+					if len(got.MultiSelect) != len(want.MultiSelect) || got.MultiSelect[0] != want.MultiSelect[0] || got.MultiSelect[1] != want.MultiSelect[1] {
+						t.Errorf("%s = %v, want %v", name, got.MultiSelect, want.MultiSelect)
+					}
+				case want.Date != nil:
+					if got.Date == nil || !got.Date.Start.Equal(want.Date.Start) {
+						t.Errorf("%s = %v, want %v", name, got.Date, want.Date)
+					}
+				case want.People != nil:
+					if len(got.People) != 1 || got.People[0].ID != want.People[0].ID {
+						t.Errorf("%s = %v, want %v", name, got.People, want.People)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAddTaskSplitsLongContent(t *testing.T) {
+	n := &archivetest.Notion{}
+	a := archive.New(&archivetest.Slack{}, n, &archivetest.OpenAI{}, archive.Options{})
+
+	content := strings.Repeat("a", 4500)
+	if _, err := a.AddTask(context.Background(), config.Modal{TitleProperty: "Name"}, archive.Task{Title: "task", Content: content, Database: database}); err != nil {
+		t.Fatal(err)
+	}
+	if got := plainText(n.Created[0].Children[0]); got != content {
+		t.Errorf("content = %d characters, want %d", len(got), len(content))
+	}
+}
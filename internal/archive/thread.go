@@ -0,0 +1,83 @@
+package archive
+
+import (
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+// Thread is a Slack thread to be archived
+type Thread struct {
+	Channel   string
+	Timestamp string
+	Permalink string
+	Messages  []slack.Message
+	// FileURLs holds the public URLs of the copied files keyed by Slack file ID
+	FileURLs map[string]string
+	// Reactor is the user who added the trigger reaction
+	Reactor string
+}
+
+// Participants returns the authors of the messages in order of appearance
+func (t Thread) Participants() []string {
+	var users []string
+	seen := map[string]bool{}
+	for _, m := range t.Messages {
+		if m.User == "" || seen[m.User] {
+			continue
+		}
+		seen[m.User] = true
+		users = append(users, m.User)
+	}
+	return users
+}
+
+// Key identifies the thread in the workspace, e.g. "C0123456789:1680000000.000100"
+func (t Thread) Key() string {
+	return ThreadKey(t.Channel, t.Timestamp)
+}
+
+// ThreadKey returns the deduplication key of the thread
+func ThreadKey(channel string, threadTimestamp string) string {
+	return channel + ":" + threadTimestamp
+}
+
+// threadLocks serializes archiving of the same thread within the process. It only saves
+// needless duplicates; AddPageToNotionDB resolves the ones created by other processes.
+// A key is removed once nobody holds or waits for its lock, so the map does not grow with every thread
+type threadLocks struct {
+	mu    sync.Mutex
+	locks map[string]*threadLock
+}
+
+// threadLock is the lock of a thread and the number of callers holding or waiting for it
+type threadLock struct {
+	sync.Mutex
+	refs int
+}
+
+func (l *threadLocks) lock(key string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*threadLock{}
+	}
+	m, ok := l.locks[key]
+	if !ok {
+		m = &threadLock{}
+		l.locks[key] = m
+	}
+	m.refs++
+	l.mu.Unlock()
+
+	m.Lock()
+	return func() {
+		m.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		m.refs--
+		if m.refs == 0 {
+			delete(l.locks, key)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package archive
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestThreadLocksRemovesReleasedKeys(t *testing.T) {
+	var l threadLocks
+	var wg sync.WaitGroup
+	running := map[string]int{}
+	var mu sync.Mutex
+
+	for i := 0; i < 100; i++ {
+		key := ThreadKey("C1", []string{"1.1", "1.2", "1.3"}[i%3])
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := l.lock(key)
+			defer unlock()
+
+			mu.Lock()
+			running[key]++
+			if running[key] > 1 {
+				t.Errorf("%s is locked by %d callers at once", key, running[key])
+			}
+			mu.Unlock()
+
+			mu.Lock()
+			running[key]--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.locks) != 0 {
+		t.Errorf("locks = %d, want none after every lock is released", len(l.locks))
+	}
+}
@@ -0,0 +1,183 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/mrkdwn"
+	"github.com/sashabaranov/go-openai"
+)
+
+// TitleStrategy generates the page title of a thread
+type TitleStrategy interface {
+	Title(ctx context.Context, thread Thread) (string, error)
+}
+
+// FirstLineTitle uses the first non-empty line of the first message as is
+type FirstLineTitle struct {
+	MaxLength int
+}
+
+// Title returns the truncated first line
+func (s FirstLineTitle) Title(ctx context.Context, thread Thread) (string, error) {
+	return truncateTitle(firstLine(firstText(thread)), s.MaxLength), nil
+}
+
+// CleanTitle uses the first line of the first message without mrkdwn, with mentions resolved to names
+// and links replaced by their labels
+type CleanTitle struct {
+	Parser    *mrkdwn.Parser
+	MaxLength int
+}
+
+// Title returns the truncated first line of the plain text
+func (s CleanTitle) Title(ctx context.Context, thread Thread) (string, error) {
+	return truncateTitle(firstLine(s.plainText(firstText(thread))), s.MaxLength), nil
+}
+
+func (s CleanTitle) plainText(text string) string {
+	var lines []string
+	for _, b := range s.Parser.ParseBlocks(text) {
+		if rtb := richTextBlock(&b); rtb != nil {
+			lines = append(lines, mrkdwn.PlainText(rtb.Text))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// maxTitleInputLength caps the thread text sent to generate the title, which only needs the gist of it
+const maxTitleInputLength = 8000
+
+// LLMTitle asks OpenAI for a short title, falling back to Clean when the request fails
+type LLMTitle struct {
+	Client    OpenAIClient
+	Model     string
+	MaxLength int
+	Clean     CleanTitle
+}
+
+// Title returns the generated title
+func (s LLMTitle) Title(ctx context.Context, thread Thread) (string, error) {
+	var sb strings.Builder
+	for _, m := range thread.Messages {
+		sb.WriteString(s.Clean.plainText(m.Text))
+		sb.WriteString("\n\n")
+	}
+
+	resp, err := s.Client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: s.Model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: "You write concise titles for Slack discussions. Reply with the title only, in the language of the discussion, without quotes.",
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: TruncateText(sb.String(), maxTitleInputLength),
+			},
+		},
+	})
+	if err == nil && len(resp.Choices) == 0 {
+		err = errors.New("openai returned no choices")
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to generate title: %v", err)
+		return s.Clean.Title(ctx, thread)
+	}
+
+	title := strings.Trim(strings.TrimSpace(resp.Choices[0].Message.Content), `"「」`)
+	return truncateTitle(firstLine(title), s.MaxLength), nil
+}
+
+// TemplateTitle expands {channel}, {date}, {author} and {first_line} in Template
+type TemplateTitle struct {
+	Template  string
+	MaxLength int
+	Clean     CleanTitle
+	archiver  *Archiver
+}
+
+// Title returns the expanded template
+func (s TemplateTitle) Title(ctx context.Context, thread Thread) (string, error) {
+	first, _ := s.Clean.Title(ctx, thread)
+
+	var date, author string
+	if len(thread.Messages) != 0 {
+		author = s.archiver.authorName(thread.Messages[0])
+	}
+	if t, err := ParseTimestamp(thread.Timestamp); err == nil {
+		if s.archiver.options.Location != nil {
+			t = t.In(s.archiver.options.Location)
+		}
+		date = t.Format("2006-01-02")
+	}
+
+	title := strings.NewReplacer(
+		"{channel}", "#"+s.archiver.directory.ChannelName(thread.Channel),
+		"{date}", date,
+		"{author}", author,
+		"{first_line}", first,
+	).Replace(s.Template)
+	return truncateTitle(title, s.MaxLength), nil
+}
+
+// TitleStrategy returns the strategy configured for the route
+func (a *Archiver) TitleStrategy(route config.Route) TitleStrategy {
+	title := config.Title{Strategy: config.TitleClean, MaxLength: config.DefaultTitleMaxLength}
+	if route.Title != nil {
+		title = *route.Title
+	}
+
+	clean := CleanTitle{
+		Parser:    &mrkdwn.Parser{Mentions: textMentions{a.directory}},
+		MaxLength: title.MaxLength,
+	}
+	switch title.Strategy {
+	case config.TitleFirstLine:
+		return FirstLineTitle{MaxLength: title.MaxLength}
+	case config.TitleLLM:
+		model := title.Model
+		if model == "" {
+			model = openai.GPT3Dot5Turbo
+		}
+		return LLMTitle{Client: a.openai, Model: model, MaxLength: title.MaxLength, Clean: clean}
+	case config.TitleTemplate:
+		return TemplateTitle{Template: title.Template, MaxLength: title.MaxLength, Clean: clean, archiver: a}
+	default:
+		return clean
+	}
+}
+
+func firstText(thread Thread) string {
+	if len(thread.Messages) == 0 {
+		return ""
+	}
+	return thread.Messages[0].Text
+}
+
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// truncateTitle shortens the title to maxLength characters with an ellipsis
+func truncateTitle(title string, maxLength int) string {
+	if maxLength <= 0 || maxLength > maxRichTextLength {
+		maxLength = maxRichTextLength
+	}
+	if utf16Len(title) <= maxLength {
+		return title
+	}
+	if maxLength < 2 {
+		// no room for the ellipsis
+		return TruncateText(title, maxLength)
+	}
+	return TruncateText(title, maxLength-1) + "…"
+}
@@ -0,0 +1,200 @@
+package archive_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/archive/archivetest"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/sashabaranov/go-openai"
+	"github.com/slack-go/slack"
+)
+
+func TestFirstLineTitle(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxLength int
+		want      string
+	}{
+		{name: "first line", text: "\n  first\nsecond", maxLength: 100, want: "first"},
+		{name: "truncated", text: "abcdef", maxLength: 4, want: "abc…"},
+		{name: "no room for the ellipsis", text: "abcdef", maxLength: 1, want: "a"},
+		{name: "default length", text: "abcdef", maxLength: 0, want: "abcdef"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thread := archive.Thread{Messages: newThread(1)}
+			thread.Messages[0].Text = tt.text
+
+			got, err := archive.FirstLineTitle{MaxLength: tt.maxLength}.Title(context.Background(), thread)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("title = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// titleArchiver returns an Archiver whose directory knows alice and #general
+func titleArchiver(openaiClient archive.OpenAIClient, location *time.Location) *archive.Archiver {
+	s := &archivetest.Slack{
+		Users: map[string]*slack.User{
+			"U1": {ID: "U1", Profile: slack.UserProfile{DisplayName: "alice"}},
+		},
+		Channels: map[string]*slack.Channel{
+			channel: {GroupConversation: slack.GroupConversation{Name: "general"}},
+		},
+	}
+	return archive.New(s, newNotion(), openaiClient, archive.Options{Routes: routes, Location: location})
+}
+
+// titleThread returns a thread of one message by U1 posted at parentTS
+func titleThread(text string) archive.Thread {
+	thread := archive.Thread{Channel: channel, Timestamp: parentTS, Messages: newThread(1)}
+	thread.Messages[0].Text = text
+	return thread
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxLength int
+		want      string
+	}{
+		{name: "mrkdwn", text: "*Release* _plan_ for ~v1~ `v2`", maxLength: 100, want: "Release plan for v1 v2"},
+		{name: "user mention", text: "<@U1> please review", maxLength: 100, want: "@alice please review"},
+		{name: "channel mention", text: "moved from <#C1>", maxLength: 100, want: "moved from #general"},
+		{name: "link label", text: "see <https://example.com|the docs>", maxLength: 100, want: "see the docs"},
+		{name: "first non-empty line", text: "\n> *quoted* title\nbody", maxLength: 100, want: "quoted title"},
+		{name: "truncated after cleaning", text: "*abcdef*", maxLength: 4, want: "abc…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := titleArchiver(&archivetest.OpenAI{}, nil)
+			route := config.Route{Title: &config.Title{Strategy: config.TitleClean, MaxLength: tt.maxLength}}
+
+			got, err := a.TitleStrategy(route).Title(context.Background(), titleThread(tt.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("title = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateTitle(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone database is not available: %v", err)
+	}
+	// 2023-03-28 23:00 UTC is already the 29th in Tokyo
+	const lateTS = "1680044400.000100"
+
+	tests := []struct {
+		name      string
+		template  string
+		ts        string
+		location  *time.Location
+		maxLength int
+		want      string
+	}{
+		{name: "every placeholder", template: "{channel} {date} {author}: {first_line}", ts: parentTS, want: "#general 2023-03-28 alice: Release plan"},
+		{name: "date in UTC", template: "{date}", ts: lateTS, want: "2023-03-28"},
+		{name: "date in the configured timezone", template: "{date}", ts: lateTS, location: tokyo, want: "2023-03-29"},
+		{name: "repeated placeholder", template: "{author}/{author}", ts: parentTS, want: "alice/alice"},
+		{name: "unknown placeholder", template: "{unknown} {first_line}", ts: parentTS, want: "{unknown} Release plan"},
+		{name: "truncated", template: "{channel}: {first_line}", ts: parentTS, maxLength: 12, want: "#general: R…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := titleArchiver(&archivetest.OpenAI{}, tt.location)
+			route := config.Route{Title: &config.Title{Strategy: config.TitleTemplate, Template: tt.template, MaxLength: tt.maxLength}}
+			thread := titleThread("*Release* plan\ndetails")
+			thread.Timestamp = tt.ts
+
+			got, err := a.TitleStrategy(route).Title(context.Background(), thread)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("title = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// noChoices is an OpenAIClient that answers without choices
+type noChoices struct{}
+
+func (noChoices) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	return openai.ChatCompletionResponse{}, nil
+}
+
+func TestLLMTitle(t *testing.T) {
+	tests := []struct {
+		name      string
+		client    archive.OpenAIClient
+		maxLength int
+		want      string
+	}{
+		{name: "reply", client: &archivetest.OpenAI{Reply: "Release plan for v2"}, want: "Release plan for v2"},
+		{name: "double quotes", client: &archivetest.OpenAI{Reply: ` "Release plan" `}, want: "Release plan"},
+		{name: "Japanese quotes", client: &archivetest.OpenAI{Reply: "「リリース計画」"}, want: "リリース計画"},
+		{name: "first line of the reply", client: &archivetest.OpenAI{Reply: "Release plan\n\nThis thread discusses..."}, want: "Release plan"},
+		{name: "truncated", client: &archivetest.OpenAI{Reply: "Release plan for v2"}, maxLength: 8, want: "Release…"},
+		{name: "error falls back to clean", client: &archivetest.OpenAI{Err: errors.New("rate limited")}, want: "fallback title"},
+		{name: "no choices falls back to clean", client: noChoices{}, want: "fallback title"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := titleArchiver(tt.client, nil)
+			route := config.Route{Title: &config.Title{Strategy: config.TitleLLM, MaxLength: tt.maxLength}}
+
+			got, err := a.TitleStrategy(route).Title(context.Background(), titleThread("*fallback* title\nbody"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("title = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLLMTitleRequest(t *testing.T) {
+	client := &archivetest.OpenAI{Reply: "title"}
+	a := titleArchiver(client, nil)
+	route := config.Route{Title: &config.Title{Strategy: config.TitleLLM, Model: "gpt-4"}}
+
+	if _, err := a.TitleStrategy(route).Title(context.Background(), titleThread("<@U1> *ship* it "+strings.Repeat("a", 9000))); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.Requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(client.Requests))
+	}
+	request := client.Requests[0]
+	if request.Model != "gpt-4" {
+		t.Errorf("model = %q, want gpt-4", request.Model)
+	}
+	content := request.Messages[1].Content
+	if !strings.HasPrefix(content, "@alice ship it ") {
+		t.Errorf("content = %.40q, want the plain text of the thread", content)
+	}
+	if n := len([]rune(content)); n > 8000 {
+		t.Errorf("content is %d characters, want at most 8000", n)
+	}
+}
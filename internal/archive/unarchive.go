@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"context"
+	"log"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// ReactionRemovedEventHandler applies Options.ReactionRemoved to the archived page of the thread
+// once the last trigger reaction has been removed from it
+func (a *Archiver) ReactionRemovedEventHandler(ctx context.Context, event *slackevents.ReactionRemovedEvent) error {
+	action := a.options.ReactionRemoved
+	if action.Action == "" || action.Action == config.ReactionRemovedNone {
+		return nil
+	}
+
+	route, ok := a.FindRoute(event.Reaction, event.Item.Channel)
+	if !ok {
+		return nil
+	}
+	if err := a.loadSchema(ctx, route); err != nil {
+		return err
+	}
+
+	// Someone else still keeps the thread archived
+	reactions, err := a.slack.GetReactions(slack.NewRefToMessage(event.Item.Channel, event.Item.Timestamp), slack.NewGetReactionsParameters())
+	if err != nil {
+		return err
+	}
+	for _, r := range reactions {
+		if route.Matches(r.Name, event.Item.Channel) && r.Count > 0 {
+			return nil
+		}
+	}
+
+	key := ThreadKey(event.Item.Channel, event.Item.Timestamp)
+	page, found, err := a.FindPageByThread(ctx, route.Database, key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	var params notion.UpdatePageParams
+	switch action.Action {
+	case config.ReactionRemovedArchive:
+		archived := true
+		params.Archived = &archived
+	case config.ReactionRemovedStatus:
+		params.DatabasePageProperties = notion.DatabasePageProperties{
+			action.StatusProperty: notion.DatabasePageProperty{
+				Select: &notion.SelectOptions{Name: action.StatusValue},
+			},
+		}
+	}
+
+	log.Printf("[INFO] Start UpdatePage: action=%s thread=%s page=%s", action.Action, key, page.ID)
+	_, err = a.notion.UpdatePage(ctx, page.ID, params)
+	return err
+}
+
+// restoredStatus returns the status select that replaces the status set by the "status" action,
+// when the page has been withdrawn and its thread is archived again
+func (a *Archiver) restoredStatus(page notion.Page) (notion.DatabasePageProperty, bool) {
+	action := a.options.ReactionRemoved
+	if action.Action != config.ReactionRemovedStatus {
+		return notion.DatabasePageProperty{}, false
+	}
+
+	var properties notion.DatabasePageProperties
+	switch p := page.Properties.(type) {
+	case notion.DatabasePageProperties:
+		properties = p
+	case *notion.DatabasePageProperties:
+		properties = *p
+	}
+	status := properties[action.StatusProperty].Select
+	if status == nil || status.Name != action.StatusValue {
+		return notion.DatabasePageProperty{}, false
+	}
+	return notion.DatabasePageProperty{Select: &notion.SelectOptions{Name: action.RestoreValue}}, true
+}
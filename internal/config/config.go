@@ -0,0 +1,217 @@
+// Package config loads the settings shared by every binary from the environment
+// and an optional YAML or JSON file.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	// Lambda images do not always ship the zoneinfo database
+	_ "time/tzdata"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Key is the environment variable name of a setting
+type Key string
+
+// Keys of the settings
+const (
+	SlackToken         Key = "SLACK_TOKEN"
+	SlackSigningSecret Key = "SLACK_SIGNING_SECRET"
+	NotionToken        Key = "NOTION_TOKEN"
+	NotionDatabase     Key = "NOTION_DATABASE"
+	OpenAIAPIKey       Key = "OPENAI_API_KEY"
+	Timezone           Key = "TIMEZONE"
+)
+
+// FileEnv is the environment variable that points to the optional config file
+const FileEnv = "CONFIG_FILE"
+
+// Config is the validated configuration passed to every handler
+type Config struct {
+	SlackToken         string `yaml:"slack_token" json:"slack_token"`
+	SlackSigningSecret string `yaml:"slack_signing_secret" json:"slack_signing_secret"`
+	NotionToken        string `yaml:"notion_token" json:"notion_token"`
+	NotionDatabase     string `yaml:"notion_database" json:"notion_database"`
+	OpenAIAPIKey       string `yaml:"openai_api_key" json:"openai_api_key"`
+
+	// ThreadIDProperty is the rich text property of the Notion databases that stores the
+	// archived thread ID ("<channel>:<thread_ts>"). Defaults to "Slack Thread ID"
+	ThreadIDProperty string `yaml:"thread_id_property" json:"thread_id_property"`
+
+	// NotionUsers maps Slack profile emails to Notion user IDs. Mentions of mapped users
+	// become Notion person mentions instead of plain names
+	NotionUsers map[string]string `yaml:"notion_users" json:"notion_users"`
+
+	// Timezone is the IANA timezone used to show message times, e.g. "Asia/Tokyo". Defaults to UTC
+	Timezone string `yaml:"timezone" json:"timezone"`
+
+	// FileStorage publishes files shared in threads so that Notion can embed them
+	FileStorage FileStorage `yaml:"file_storage" json:"file_storage"`
+
+	// Properties fills database properties with Slack metadata for routes without their own mapping
+	Properties PropertyMapping `yaml:"properties" json:"properties"`
+
+	// Title configures how page titles are generated for routes without their own setting
+	Title Title `yaml:"title" json:"title"`
+
+	// Summarizers defines named summarizers. "none" is always available
+	Summarizers map[string]Summarizer `yaml:"summarizers" json:"summarizers"`
+	// Summary is the summarizer used by routes without their own. Defaults to "none"
+	Summary string `yaml:"summary" json:"summary"`
+	// ChannelSummaries overrides the summarizer per channel ID
+	ChannelSummaries map[string]string `yaml:"channel_summaries" json:"channel_summaries"`
+	// Prompt configures the summary prompt of routes without their own
+	Prompt Prompt `yaml:"prompt" json:"prompt"`
+	// Extract adds decisions, action items and open questions extracted by the summarizer to the page.
+	// Routes can override it
+	Extract bool `yaml:"extract" json:"extract"`
+
+	// Modal configures the task modal of the slash command
+	Modal Modal `yaml:"modal" json:"modal"`
+
+	// Routes maps trigger reactions to Notion databases. Only configurable in the config file
+	Routes []Route `yaml:"routes" json:"routes"`
+
+	// Notify posts the page URL, or the error, to Slack after a thread is archived
+	Notify Notify `yaml:"notify" json:"notify"`
+	// ReactionRemoved is applied to the archived page when the trigger reaction is removed
+	ReactionRemoved ReactionRemoved `yaml:"reaction_removed" json:"reaction_removed"`
+}
+
+// Load reads the file named by CONFIG_FILE (if any), overrides it with the environment
+// and returns an error naming every required key that is still empty.
+// A CONFIG_FILE that does not exist is skipped, so that deployments without the file
+// are configured by the environment alone.
+func Load(required ...Key) (*Config, error) {
+	cfg := &Config{}
+
+	if path := os.Getenv(FileEnv); path != "" {
+		if err := cfg.readFile(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	for key, field := range cfg.fields() {
+		if v, ok := os.LookupEnv(string(key)); ok {
+			*field = v
+		}
+	}
+
+	if err := cfg.Require(required...); err != nil {
+		return nil, err
+	}
+	if err := cfg.Prompt.setDefaults("prompt"); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateRoutes(); err != nil {
+		return nil, err
+	}
+	if err := cfg.ReactionRemoved.setDefaults(); err != nil {
+		return nil, err
+	}
+	if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+		return nil, fmt.Errorf("timezone: %w", err)
+	}
+	if err := cfg.FileStorage.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Properties.validate("properties"); err != nil {
+		return nil, err
+	}
+	if err := cfg.Title.setDefaults("title"); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateLLMTitles(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateSummarizers(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Modal.setDefaults(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Notify.setDefaults(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// MustLoad is like Load but exits the process when the configuration is invalid
+func MustLoad(required ...Key) *Config {
+	cfg, err := Load(required...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// Require returns an error naming every key that is empty.
+// NOTION_DATABASE is satisfied by configured routes as well.
+func (c *Config) Require(keys ...Key) error {
+	fields := c.fields()
+
+	var missing []string
+	for _, key := range keys {
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("unknown config key: %s", key)
+		}
+		if key == NotionDatabase && len(c.Routes) != 0 {
+			continue
+		}
+		if strings.TrimSpace(*field) == "" {
+			missing = append(missing, string(key))
+		}
+	}
+
+	if len(missing) != 0 {
+		return fmt.Errorf("missing required config: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Location returns the configured timezone
+func (c *Config) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func (c *Config) fields() map[Key]*string {
+	return map[Key]*string{
+		SlackToken:         &c.SlackToken,
+		SlackSigningSecret: &c.SlackSigningSecret,
+		NotionToken:        &c.NotionToken,
+		NotionDatabase:     &c.NotionDatabase,
+		OpenAIAPIKey:       &c.OpenAIAPIKey,
+		Timezone:           &c.Timezone,
+	}
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, c)
+	default:
+		err = yaml.Unmarshal(data, c)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,404 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+)
+
+// load writes content to a config file and loads it with the required keys
+func load(t *testing.T, content string, required ...config.Key) (*config.Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.FileEnv, path)
+	t.Setenv(string(config.OpenAIAPIKey), "")
+	return config.Load(required...)
+}
+
+// unsetEnv unsets the environment variables of the keys for the test, since an empty variable still overrides the file
+func unsetEnv(t *testing.T, keys ...config.Key) {
+	t.Helper()
+	for _, key := range keys {
+		// t.Setenv restores the original value after the test
+		t.Setenv(string(key), "")
+		os.Unsetenv(string(key))
+	}
+}
+
+func TestLoadRequire(t *testing.T) {
+	required := []config.Key{config.SlackToken, config.SlackSigningSecret, config.NotionToken, config.NotionDatabase}
+
+	tests := []struct {
+		name    string
+		content string
+		env     map[config.Key]string
+		// wantErr is the whole error, or empty when every required key is set
+		wantErr string
+	}{
+		{
+			name:    "every key missing",
+			wantErr: "missing required config: SLACK_TOKEN, SLACK_SIGNING_SECRET, NOTION_TOKEN, NOTION_DATABASE",
+		},
+		{
+			name:    "some keys missing",
+			content: "slack_token: xoxb\nnotion_database: db-1\n",
+			wantErr: "missing required config: SLACK_SIGNING_SECRET, NOTION_TOKEN",
+		},
+		{
+			name:    "blank value",
+			content: "slack_token: xoxb\nslack_signing_secret: \"  \"\nnotion_token: secret\nnotion_database: db-1\n",
+			wantErr: "missing required config: SLACK_SIGNING_SECRET",
+		},
+		{
+			name:    "file",
+			content: "slack_token: xoxb\nslack_signing_secret: signing\nnotion_token: secret\nnotion_database: db-1\n",
+		},
+		{
+			name: "environment",
+			env: map[config.Key]string{
+				config.SlackToken:         "xoxb",
+				config.SlackSigningSecret: "signing",
+				config.NotionToken:        "secret",
+				config.NotionDatabase:     "db-1",
+			},
+		},
+		{
+			name:    "file and environment",
+			content: "slack_token: xoxb\nnotion_token: secret\n",
+			env:     map[config.Key]string{config.SlackSigningSecret: "signing", config.NotionDatabase: "db-1"},
+		},
+		{
+			name:    "notion_database satisfied by routes",
+			content: "slack_token: xoxb\nslack_signing_secret: signing\nnotion_token: secret\nroutes:\n  - reaction: bug\n    database: db-bugs\n",
+		},
+		{
+			name:    "empty environment variable overrides the file",
+			content: "slack_token: xoxb\nslack_signing_secret: signing\nnotion_token: secret\nnotion_database: db-1\n",
+			env:     map[config.Key]string{config.NotionToken: ""},
+			wantErr: "missing required config: NOTION_TOKEN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unsetEnv(t, required...)
+			for key, value := range tt.env {
+				t.Setenv(string(key), value)
+			}
+
+			_, err := load(t, tt.content, required...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	unsetEnv(t, config.SlackToken, config.SlackSigningSecret, config.NotionToken, config.NotionDatabase, config.Timezone)
+	t.Setenv(string(config.SlackToken), "env-token")
+	t.Setenv(string(config.Timezone), "Asia/Tokyo")
+
+	cfg, err := load(t, `
+slack_token: file-token
+slack_signing_secret: file-secret
+timezone: Europe/London
+`, config.SlackToken, config.SlackSigningSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.SlackToken != "env-token" {
+		t.Errorf("slack_token = %q, want the environment variable", cfg.SlackToken)
+	}
+	if cfg.SlackSigningSecret != "file-secret" {
+		t.Errorf("slack_signing_secret = %q, want the file value", cfg.SlackSigningSecret)
+	}
+	if got := cfg.Location().String(); got != "Asia/Tokyo" {
+		t.Errorf("location = %q, want Asia/Tokyo", got)
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	unsetEnv(t, config.SlackToken, config.SlackSigningSecret)
+	t.Setenv(config.FileEnv, "")
+	t.Setenv(string(config.SlackToken), "env-token")
+
+	_, err := config.Load(config.SlackToken, config.SlackSigningSecret)
+	if err == nil || err.Error() != "missing required config: SLACK_SIGNING_SECRET" {
+		t.Fatalf("err = %v, want SLACK_SIGNING_SECRET to be missing", err)
+	}
+
+	t.Setenv(string(config.SlackSigningSecret), "signing")
+	cfg, err := config.Load(config.SlackToken, config.SlackSigningSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SlackToken != "env-token" || cfg.SlackSigningSecret != "signing" {
+		t.Errorf("config = %+v, want the environment variables", cfg)
+	}
+}
+
+func TestLoadSkipsMissingFile(t *testing.T) {
+	unsetEnv(t, config.SlackToken)
+	t.Setenv(config.FileEnv, filepath.Join(t.TempDir(), "config.yml"))
+	t.Setenv(string(config.SlackToken), "env-token")
+
+	cfg, err := config.Load(config.SlackToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SlackToken != "env-token" {
+		t.Errorf("slack_token = %q, want the environment variable", cfg.SlackToken)
+	}
+}
+
+func TestLoadRejectsUnreadableFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("routes: ["), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.FileEnv, path)
+
+	if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "failed to parse config file") {
+		t.Fatalf("err = %v, want the parse error", err)
+	}
+}
+
+func TestRequireUnknownKey(t *testing.T) {
+	cfg := &config.Config{}
+	if err := cfg.Require(config.Key("UNKNOWN")); err == nil || !strings.Contains(err.Error(), "unknown config key: UNKNOWN") {
+		t.Fatalf("err = %v, want the unknown key", err)
+	}
+}
+
+func TestRoutePromptInheritsPrompt(t *testing.T) {
+	cfg, err := load(t, `
+prompt:
+  system: global system
+  language: English
+  length: 300
+routes:
+  - reaction: memo
+    database: db-1
+    prompt:
+      length: 50
+  - reaction: bug
+    database: db-2
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []config.Prompt{
+		{System: "global system", Instructions: config.DefaultPromptInstructions, Language: "English", Length: 50},
+		{System: "global system", Instructions: config.DefaultPromptInstructions, Language: "English", Length: 300},
+	}
+	for i, r := range cfg.RouteTable() {
+		if *r.Prompt != want[i] {
+			t.Errorf("routes[%d].prompt = %+v, want %+v", i, *r.Prompt, want[i])
+		}
+	}
+}
+
+func TestLoadRejectsInvalidTitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "max_length below 2",
+			content: "title:\n  max_length: 1\n",
+			want:    "title.max_length must be at least 2",
+		},
+		{
+			name:    "route max_length below 2",
+			content: "routes:\n  - reaction: memo\n    database: db-1\n    title:\n      max_length: 1\n",
+			want:    "routes[0].title.max_length must be at least 2",
+		},
+		{
+			name:    "llm without the API key",
+			content: "title:\n  strategy: llm\n",
+			want:    "title: OPENAI_API_KEY is required for the llm strategy",
+		},
+		{
+			name:    "route llm without the API key",
+			content: "routes:\n  - reaction: memo\n    database: db-1\n    title:\n      strategy: llm\n",
+			want:    "routes[0].title: OPENAI_API_KEY is required for the llm strategy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.content)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsExtractWithoutExtractor(t *testing.T) {
+	const summarizers = `
+summarizers:
+  local:
+    type: openai_compatible
+    base_url: http://localhost:11434/v1
+  plain:
+    type: none
+`
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "extract with an openai_compatible summarizer",
+			content: summarizers + "summary: local\nextract: true\n",
+		},
+		{
+			name:    "global extract without a summarizer",
+			content: "extract: true\n",
+			want:    `extract: extract requires an openai or openai_compatible summarizer, but summary is "none"`,
+		},
+		{
+			name:    "route extract with a none summarizer",
+			content: summarizers + "routes:\n  - reaction: memo\n    database: db-1\n    summary: plain\n    extract: true\n",
+			want:    `routes[0] (memo): extract requires an openai or openai_compatible summarizer, but summary is "plain"`,
+		},
+		{
+			name:    "route inherits the global extract",
+			content: summarizers + "summary: local\nextract: true\nroutes:\n  - reaction: memo\n    database: db-1\n  - reaction: bug\n    database: db-2\n    summary: plain\n",
+			want:    `routes[1] (bug): extract requires an openai or openai_compatible summarizer, but summary is "plain"`,
+		},
+		{
+			name:    "channel override without extraction",
+			content: summarizers + "summary: local\nextract: true\nchannel_summaries:\n  C1: plain\n",
+			want:    `extract: extract requires an openai or openai_compatible summarizer, but channel_summaries.C1 is "plain"`,
+		},
+		{
+			name:    "channel override outside the route",
+			content: summarizers + "summary: local\nchannel_summaries:\n  C1: plain\nroutes:\n  - reaction: memo\n    database: db-1\n    channels: [C2]\n    extract: true\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.content)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("err = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsInvalidPrompt(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// want is a substring of the error
+		want string
+	}{
+		{
+			name:    "valid fields",
+			content: "prompt:\n  system: \"{{.Channel}} {{range .Participants}}{{.}}{{end}}\"\n  instructions: \"{{.Language}} {{.Length}} {{range .Messages}}{{.UserID}} {{.Author}} {{.Time}} {{.Text}}{{end}}\"\n",
+		},
+		{
+			name:    "syntax error",
+			content: "prompt:\n  system: \"{{.Channel\"\n",
+			want:    "prompt.system: template: system:1: unclosed action",
+		},
+		{
+			name:    "unknown field",
+			content: "prompt:\n  instructions: \"{{.Lenght}}\"\n",
+			want:    "can't evaluate field Lenght",
+		},
+		{
+			name:    "unknown message field",
+			content: "routes:\n  - reaction: memo\n    database: db-1\n    prompt:\n      instructions: \"{{range .Messages}}{{.Txt}}{{end}}\"\n",
+			want:    "can't evaluate field Txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.content)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("err = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveDatabases(t *testing.T) {
+	cfg := &config.Config{
+		Modal: config.Modal{Databases: []config.ModalDatabase{{ID: "db-2", Name: "Bugs"}}},
+		Routes: []config.Route{
+			{Reaction: "memo", Database: "db-1"},
+			{Reaction: "bug", Database: "db-2", Channels: []string{"C1"}},
+			{Reaction: "ladybug", Database: "db-2", Channels: []string{"C1"}},
+			{Reaction: "todo", Database: "db-3", Channels: []string{"C2"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		channel string
+		want    []config.ModalDatabase
+	}{
+		{
+			name:    "routes of the channel named after modal.databases",
+			channel: "C1",
+			want:    []config.ModalDatabase{{ID: "db-1", Name: ":memo:"}, {ID: "db-2", Name: "Bugs"}},
+		},
+		{
+			name:    "routes of another channel named after their reactions",
+			channel: "C2",
+			want:    []config.ModalDatabase{{ID: "db-1", Name: ":memo:"}, {ID: "db-3", Name: ":todo:"}},
+		},
+		{
+			name:    "catch-all route only",
+			channel: "C3",
+			want:    []config.ModalDatabase{{ID: "db-1", Name: ":memo:"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cfg.SaveDatabases(tt.channel)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SaveDatabases(%q) = %v, want %v", tt.channel, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("SaveDatabases(%q)[%d] = %v, want %v", tt.channel, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
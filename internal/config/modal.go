@@ -0,0 +1,91 @@
+package config
+
+import "fmt"
+
+// Modal configures the task modal opened by the slash command and the Notion properties its answers are saved to
+type Modal struct {
+	// Databases are the choices of the database picker. Defaults to the databases of the routes
+	Databases []ModalDatabase `yaml:"databases" json:"databases"`
+	// Tags are the options of the tags multi-select. The field is hidden when empty
+	Tags []string `yaml:"tags" json:"tags"`
+	// TitleProperty is the title property. Defaults to "Name"
+	TitleProperty string `yaml:"title_property" json:"title_property"`
+	// TagsProperty is the multi_select property of the tags. Defaults to "Tags"
+	TagsProperty string `yaml:"tags_property" json:"tags_property"`
+	// DueProperty is the date property of the due date. Defaults to "Due"
+	DueProperty string `yaml:"due_property" json:"due_property"`
+	// AssigneeProperty is the people property of the assignee, which needs notion_users. Defaults to "Assignee"
+	AssigneeProperty string `yaml:"assignee_property" json:"assignee_property"`
+}
+
+// ModalDatabase is a choice of the database picker
+type ModalDatabase struct {
+	ID   string `yaml:"id" json:"id"`
+	Name string `yaml:"name" json:"name"`
+}
+
+func (m *Modal) setDefaults() error {
+	for i, db := range m.Databases {
+		if db.ID == "" {
+			return fmt.Errorf("modal.databases[%d].id is required", i)
+		}
+		if db.Name == "" {
+			m.Databases[i].Name = db.ID
+		}
+	}
+	if m.TitleProperty == "" {
+		m.TitleProperty = "Name"
+	}
+	if m.TagsProperty == "" {
+		m.TagsProperty = "Tags"
+	}
+	if m.DueProperty == "" {
+		m.DueProperty = "Due"
+	}
+	if m.AssigneeProperty == "" {
+		m.AssigneeProperty = "Assignee"
+	}
+	return nil
+}
+
+// ModalDatabases returns Modal.Databases, or the databases of the routes named after their reactions
+func (c *Config) ModalDatabases() []ModalDatabase {
+	if len(c.Modal.Databases) > 0 {
+		return c.Modal.Databases
+	}
+	return c.routeDatabases("")
+}
+
+// SaveDatabases returns the databases of the routes enabled in the channel, which are the choices of the
+// "Save to Notion" modal. Only routed databases have their schema checked before saving.
+// They are named like Modal.Databases when listed there, or after their reactions
+func (c *Config) SaveDatabases(channel string) []ModalDatabase {
+	databases := c.routeDatabases(channel)
+	for i, db := range databases {
+		for _, m := range c.Modal.Databases {
+			if m.ID == db.ID {
+				databases[i].Name = m.Name
+				break
+			}
+		}
+	}
+	return databases
+}
+
+// routeDatabases returns the databases of the routes enabled in the channel, or of every route when channel is empty
+func (c *Config) routeDatabases(channel string) []ModalDatabase {
+	var databases []ModalDatabase
+	index := map[string]int{}
+	for _, r := range c.RouteTable() {
+		if r.Database == "" || (channel != "" && !r.MatchesChannel(channel)) {
+			continue
+		}
+		if i, ok := index[r.Database]; ok {
+			databases[i].Name += " :" + r.Reaction + ":"
+			continue
+		}
+		index[r.Database] = len(databases)
+		databases = append(databases, ModalDatabase{ID: r.Database, Name: ":" + r.Reaction + ":"})
+	}
+	return databases
+}
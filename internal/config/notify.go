@@ -0,0 +1,27 @@
+package config
+
+import "fmt"
+
+// Where the result of archiving a thread is posted
+const (
+	NotifyThread    = "thread"    // reply in the thread
+	NotifyEphemeral = "ephemeral" // ephemeral message to the user who archived the thread
+	NotifyNone      = "none"
+)
+
+// Notify configures the confirmation posted to Slack after a thread is archived
+type Notify struct {
+	// Mode is one of "thread" (default), "ephemeral" or "none"
+	Mode string `yaml:"mode" json:"mode"`
+}
+
+func (n *Notify) setDefaults() error {
+	switch n.Mode {
+	case "":
+		n.Mode = NotifyThread
+	case NotifyThread, NotifyEphemeral, NotifyNone:
+	default:
+		return fmt.Errorf("notify.mode: unknown mode %q", n.Mode)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// Defaults of Prompt, a 100 character summary in Japanese. The templates are written in English
+// so that only Language and Length decide the output
+const (
+	DefaultPromptSystem       = "You are a skilled editor who rewrites long texts into concise summaries that keep the key points."
+	DefaultPromptInstructions = "Summarize the following conversation in {{.Language}} within {{.Length}} characters.\n\n{{range .Messages}}{{.Text}}\n\n{{end}}"
+	DefaultPromptLanguage     = "Japanese"
+	DefaultPromptLength       = 100
+)
+
+// Prompt configures the prompt of the summarizers.
+// System and Instructions are Go text/template templates executed with PromptData:
+// .Channel, .Participants, .Messages (each with .Author, .Time and .Text), .Language and .Length
+type Prompt struct {
+	// System is the system message. Defaults to DefaultPromptSystem
+	System string `yaml:"system" json:"system"`
+	// Instructions is the user message, which should render .Messages. Defaults to DefaultPromptInstructions
+	Instructions string `yaml:"instructions" json:"instructions"`
+	// Language is the output language. Defaults to "Japanese"
+	Language string `yaml:"language" json:"language"`
+	// Length is the target length of the summary in characters. Defaults to 100
+	Length int `yaml:"length" json:"length"`
+}
+
+func (p *Prompt) setDefaults(path string) error {
+	p.inherit(Prompt{
+		System:       DefaultPromptSystem,
+		Instructions: DefaultPromptInstructions,
+		Language:     DefaultPromptLanguage,
+		Length:       DefaultPromptLength,
+	})
+	if err := checkTemplate("system", p.System); err != nil {
+		return fmt.Errorf("%s.system: %w", path, err)
+	}
+	if err := checkTemplate("instructions", p.Instructions); err != nil {
+		return fmt.Errorf("%s.instructions: %w", path, err)
+	}
+	return nil
+}
+
+// PromptData is the data the summarizers execute the prompt templates with
+type PromptData struct {
+	// Channel is the channel name
+	Channel string
+	// Participants are the names of the authors in order of appearance
+	Participants []string
+	Messages     []PromptMessage
+	Language     string
+	Length       int
+}
+
+// PromptMessage is a message of PromptData
+type PromptMessage struct {
+	// UserID is the Slack user ID of the author, empty for bots
+	UserID string
+	Author string
+	// Time is the local time the message was posted, e.g. "2023-04-01 12:34"
+	Time string
+	Text string
+}
+
+// checkTemplate parses the template and executes it with zero values, so that unknown fields such as
+// {{.Lenght}} fail when the config is loaded instead of when a thread is summarized.
+// The lists have one element so that the bodies of range actions are executed as well
+func checkTemplate(name string, text string) error {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(io.Discard, PromptData{
+		Participants: []string{""},
+		Messages:     []PromptMessage{{}},
+	})
+}
+
+// inherit fills the fields that are not set with those of base
+func (p *Prompt) inherit(base Prompt) {
+	if p.System == "" {
+		p.System = base.System
+	}
+	if p.Instructions == "" {
+		p.Instructions = base.Instructions
+	}
+	if p.Language == "" {
+		p.Language = base.Language
+	}
+	if p.Length <= 0 {
+		p.Length = base.Length
+	}
+}
@@ -0,0 +1,35 @@
+package config
+
+import "fmt"
+
+// Sources of the values mapped to Notion database properties
+const (
+	SourceChannel      = "channel"      // channel name
+	SourceParticipants = "participants" // authors of the messages
+	SourceStartedAt    = "started_at"   // time of the first message
+	SourcePermalink    = "permalink"    // permalink of the thread
+	SourceReplyCount   = "reply_count"  // number of replies
+	SourceReactor      = "reactor"      // user who added the trigger reaction
+)
+
+var propertySources = map[string]bool{
+	SourceChannel:      true,
+	SourceParticipants: true,
+	SourceStartedAt:    true,
+	SourcePermalink:    true,
+	SourceReplyCount:   true,
+	SourceReactor:      true,
+}
+
+// PropertyMapping maps Notion property names to the Slack metadata filled into them,
+// e.g. {"Channel": "channel", "Started": "started_at"}
+type PropertyMapping map[string]string
+
+func (m PropertyMapping) validate(path string) error {
+	for property, source := range m {
+		if !propertySources[source] {
+			return fmt.Errorf("%s.%s: unknown source %q", path, property, source)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultReaction is the trigger reaction used when no routes are configured
+const DefaultReaction = "slack-to-notion"
+
+// Route maps a trigger reaction (and optionally channels) to a Notion database
+type Route struct {
+	// Reaction is the emoji name without colons, e.g. "bug"
+	Reaction string `yaml:"reaction" json:"reaction"`
+	// Database is the Notion database ID that pages are created in
+	Database string `yaml:"database" json:"database"`
+	// Channels limits the route to these channel IDs. Empty matches every channel
+	Channels []string `yaml:"channels" json:"channels"`
+	// Properties fills database properties with Slack metadata. Defaults to Config.Properties
+	Properties PropertyMapping `yaml:"properties" json:"properties"`
+	// Title overrides Config.Title
+	Title *Title `yaml:"title" json:"title"`
+	// Summary is the name of the summarizer. Defaults to Config.Summary
+	Summary string `yaml:"summary" json:"summary"`
+	// Prompt overrides the fields of Config.Prompt that it sets
+	Prompt *Prompt `yaml:"prompt" json:"prompt"`
+	// Extract overrides Config.Extract
+	Extract *bool `yaml:"extract" json:"extract"`
+}
+
+// Matches reports whether the route handles the reaction in the channel
+func (r Route) Matches(reaction string, channel string) bool {
+	if normalizeReaction(r.Reaction) != normalizeReaction(reaction) {
+		return false
+	}
+	return r.MatchesChannel(channel)
+}
+
+// MatchesChannel reports whether the route is enabled in the channel
+func (r Route) MatchesChannel(channel string) bool {
+	if len(r.Channels) == 0 {
+		return true
+	}
+	for _, c := range r.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteTable returns the configured routes, or a single route from DefaultReaction to
+// NOTION_DATABASE when none are configured.
+func (c *Config) RouteTable() []Route {
+	routes := c.Routes
+	if len(routes) == 0 {
+		routes = []Route{{Reaction: DefaultReaction, Database: c.NotionDatabase}}
+	}
+
+	table := make([]Route, len(routes))
+	for i, r := range routes {
+		if r.Properties == nil {
+			r.Properties = c.Properties
+		}
+		if r.Title == nil {
+			title := c.Title
+			r.Title = &title
+		}
+		if r.Prompt == nil {
+			prompt := c.Prompt
+			r.Prompt = &prompt
+		}
+		if r.Extract == nil {
+			extract := c.Extract
+			r.Extract = &extract
+		}
+		if r.Summary == "" {
+			r.Summary = c.Summary
+		}
+		if r.Summary == "" {
+			r.Summary = SummarizerNone
+		}
+		table[i] = r
+	}
+	return table
+}
+
+// DefaultDatabase returns NOTION_DATABASE, or the database of the first route when it is empty
+func (c *Config) DefaultDatabase() string {
+	if c.NotionDatabase != "" || len(c.Routes) == 0 {
+		return c.NotionDatabase
+	}
+	return c.Routes[0].Database
+}
+
+func (c *Config) validateRoutes() error {
+	for i, r := range c.Routes {
+		if normalizeReaction(r.Reaction) == "" {
+			return fmt.Errorf("routes[%d]: reaction is required", i)
+		}
+		if strings.TrimSpace(r.Database) == "" {
+			return fmt.Errorf("routes[%d] (%s): database is required", i, r.Reaction)
+		}
+		if err := r.Properties.validate(fmt.Sprintf("routes[%d].properties", i)); err != nil {
+			return err
+		}
+		if r.Title != nil {
+			if err := r.Title.setDefaults(fmt.Sprintf("routes[%d].title", i)); err != nil {
+				return err
+			}
+		}
+		if r.Prompt != nil {
+			// Fields that are not set fall back to Config.Prompt, which has its defaults set already
+			r.Prompt.inherit(c.Prompt)
+			if err := r.Prompt.setDefaults(fmt.Sprintf("routes[%d].prompt", i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func normalizeReaction(reaction string) string {
+	return strings.Trim(strings.TrimSpace(reaction), ":")
+}
+
+// Actions applied to the archived page when the trigger reaction is removed
+const (
+	ReactionRemovedNone    = "none"
+	ReactionRemovedArchive = "archive"
+	ReactionRemovedStatus  = "status"
+)
+
+// ReactionRemoved configures what happens to the archived page when the trigger reaction is removed
+type ReactionRemoved struct {
+	// Action is one of "none" (default), "archive" or "status"
+	Action string `yaml:"action" json:"action"`
+	// StatusProperty is the select property updated by the "status" action. Defaults to "Status"
+	StatusProperty string `yaml:"status_property" json:"status_property"`
+	// StatusValue is the select option set by the "status" action. Defaults to "Withdrawn"
+	StatusValue string `yaml:"status_value" json:"status_value"`
+	// RestoreValue replaces StatusValue when the trigger reaction is added to the withdrawn thread again.
+	// Defaults to "Archived"
+	RestoreValue string `yaml:"restore_value" json:"restore_value"`
+}
+
+func (r *ReactionRemoved) setDefaults() error {
+	switch r.Action {
+	case "":
+		r.Action = ReactionRemovedNone
+	case ReactionRemovedNone, ReactionRemovedArchive, ReactionRemovedStatus:
+	default:
+		return fmt.Errorf("reaction_removed.action: unknown action %q", r.Action)
+	}
+	if r.StatusProperty == "" {
+		r.StatusProperty = "Status"
+	}
+	if r.StatusValue == "" {
+		r.StatusValue = "Withdrawn"
+	}
+	if r.RestoreValue == "" {
+		r.RestoreValue = "Archived"
+	}
+	return nil
+}
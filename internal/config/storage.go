@@ -0,0 +1,46 @@
+package config
+
+import "fmt"
+
+// Types of FileStorage
+const (
+	FileStorageNone  = "none"
+	FileStorageLocal = "local"
+	FileStorageS3    = "s3"
+)
+
+// FileStorage configures where files shared in threads are copied to
+type FileStorage struct {
+	// Type is one of "none" (default), "local" or "s3"
+	Type string `yaml:"type" json:"type"`
+	// BaseURL is the public URL the stored files are served from
+	BaseURL string `yaml:"base_url" json:"base_url"`
+
+	// Dir is the directory of the "local" storage
+	Dir string `yaml:"dir" json:"dir"`
+
+	// Bucket, Region, Endpoint and Prefix configure the "s3" storage.
+	// Endpoint is only needed for S3 compatible stores
+	Bucket   string `yaml:"bucket" json:"bucket"`
+	Region   string `yaml:"region" json:"region"`
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	Prefix   string `yaml:"prefix" json:"prefix"`
+}
+
+func (s *FileStorage) validate() error {
+	switch s.Type {
+	case "":
+		s.Type = FileStorageNone
+	case FileStorageNone, FileStorageS3:
+	case FileStorageLocal:
+		if s.Dir == "" {
+			return fmt.Errorf("file_storage.dir is required for local storage")
+		}
+		if s.BaseURL == "" {
+			return fmt.Errorf("file_storage.base_url is required for local storage")
+		}
+	default:
+		return fmt.Errorf("file_storage.type: unknown type %q", s.Type)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Types of Summarizer
+const (
+	SummarizerNone             = "none"
+	SummarizerOpenAI           = "openai"
+	SummarizerOpenAICompatible = "openai_compatible"
+)
+
+// Summarizer configures a named summarizer
+type Summarizer struct {
+	// Type is one of "none", "openai" or "openai_compatible"
+	Type string `yaml:"type" json:"type"`
+	// Model is the chat model. Defaults to gpt-3.5-turbo
+	Model string `yaml:"model" json:"model"`
+	// BaseURL is the endpoint of an OpenAI compatible server, e.g. "http://localhost:11434/v1" for Ollama
+	BaseURL string `yaml:"base_url" json:"base_url"`
+	// APIKey defaults to OPENAI_API_KEY for the "openai" type
+	APIKey string `yaml:"api_key" json:"api_key"`
+	// MaxInputTokens is the estimated tokens of messages sent in one request.
+	// Longer threads are summarized in chunks. Defaults to 3000, set it higher for models with a larger context window
+	MaxInputTokens int `yaml:"max_input_tokens" json:"max_input_tokens"`
+}
+
+func (c *Config) validateSummarizers() error {
+	for name, s := range c.Summarizers {
+		if s.MaxInputTokens < 0 {
+			return fmt.Errorf("summarizers.%s.max_input_tokens must not be negative", name)
+		}
+		switch s.Type {
+		case SummarizerNone, SummarizerOpenAI:
+		case SummarizerOpenAICompatible:
+			if s.BaseURL == "" {
+				return fmt.Errorf("summarizers.%s.base_url is required for openai_compatible", name)
+			}
+		default:
+			return fmt.Errorf("summarizers.%s.type: unknown type %q", name, s.Type)
+		}
+	}
+
+	known := func(name string) bool {
+		_, ok := c.Summarizers[name]
+		return ok || name == "" || name == SummarizerNone
+	}
+	if !known(c.Summary) {
+		return fmt.Errorf("summary: unknown summarizer %q", c.Summary)
+	}
+	for i, r := range c.Routes {
+		if !known(r.Summary) {
+			return fmt.Errorf("routes[%d].summary: unknown summarizer %q", i, r.Summary)
+		}
+	}
+	for channel, name := range c.ChannelSummaries {
+		if !known(name) {
+			return fmt.Errorf("channel_summaries.%s: unknown summarizer %q", channel, name)
+		}
+	}
+	return c.validateExtract()
+}
+
+// validateExtract rejects routes that extract with a summarizer that cannot extract,
+// including the summarizers that channel_summaries chooses in the channels of the route
+func (c *Config) validateExtract() error {
+	canExtract := func(name string) bool {
+		s, ok := c.Summarizers[name]
+		return ok && (s.Type == SummarizerOpenAI || s.Type == SummarizerOpenAICompatible)
+	}
+	channels := make([]string, 0, len(c.ChannelSummaries))
+	for channel := range c.ChannelSummaries {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+
+	for i, r := range c.RouteTable() {
+		if !*r.Extract {
+			continue
+		}
+		field := fmt.Sprintf("routes[%d] (%s)", i, r.Reaction)
+		if len(c.Routes) == 0 {
+			field = "extract"
+		}
+		if !canExtract(r.Summary) {
+			return fmt.Errorf("%s: extract requires an openai or openai_compatible summarizer, but summary is %q", field, r.Summary)
+		}
+		for _, channel := range channels {
+			if name := c.ChannelSummaries[channel]; r.MatchesChannel(channel) && !canExtract(name) {
+				return fmt.Errorf("%s: extract requires an openai or openai_compatible summarizer, but channel_summaries.%s is %q", field, channel, name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import "fmt"
+
+// Title strategies
+const (
+	TitleFirstLine = "first_line" // first line of the raw text
+	TitleClean     = "clean"      // first line without mrkdwn, with mentions resolved
+	TitleLLM       = "llm"        // generated by OpenAI
+	TitleTemplate  = "template"   // Template with {channel}, {date}, {author} and {first_line}
+)
+
+// DefaultTitleMaxLength is the default maximum length of generated titles
+const DefaultTitleMaxLength = 100
+
+// Title configures how page titles are generated
+type Title struct {
+	// Strategy is one of "first_line", "clean" (default), "llm" or "template"
+	Strategy string `yaml:"strategy" json:"strategy"`
+	// MaxLength truncates the title. Defaults to 100, and must be at least 2
+	MaxLength int `yaml:"max_length" json:"max_length"`
+	// Template is used by the "template" strategy, e.g. "{channel} {date}: {first_line}"
+	Template string `yaml:"template" json:"template"`
+	// Model is the OpenAI model used by the "llm" strategy. Defaults to gpt-3.5-turbo
+	Model string `yaml:"model" json:"model"`
+}
+
+func (t *Title) setDefaults(path string) error {
+	switch t.Strategy {
+	case "":
+		t.Strategy = TitleClean
+	case TitleFirstLine, TitleClean, TitleLLM:
+	case TitleTemplate:
+		if t.Template == "" {
+			return fmt.Errorf("%s.template is required for the template strategy", path)
+		}
+	default:
+		return fmt.Errorf("%s.strategy: unknown strategy %q", path, t.Strategy)
+	}
+	switch {
+	case t.MaxLength == 0:
+		t.MaxLength = DefaultTitleMaxLength
+	case t.MaxLength < 2:
+		// one character is left for the title besides the ellipsis
+		return fmt.Errorf("%s.max_length must be at least 2", path)
+	}
+	return nil
+}
+
+// validateLLMTitles requires OPENAI_API_KEY when any title is generated by OpenAI
+func (c *Config) validateLLMTitles() error {
+	if c.OpenAIAPIKey != "" {
+		return nil
+	}
+	if c.Title.Strategy == TitleLLM {
+		return fmt.Errorf("title: %s is required for the llm strategy", OpenAIAPIKey)
+	}
+	for i, r := range c.Routes {
+		if r.Title != nil && r.Title.Strategy == TitleLLM {
+			return fmt.Errorf("routes[%d].title: %s is required for the llm strategy", i, OpenAIAPIKey)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Package modal defines the Slack modals shared by the slash_command and interaction Lambdas,
+// so that both read and write the same block and action IDs.
+package modal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/slack-go/slack"
+)
+
+// Callback IDs of the task modal and of the global shortcut that opens it like the slash command
+const (
+	CallbackTask         = "notion_task"
+	CallbackTaskShortcut = "add_notion_task"
+)
+
+// Block and action IDs of the task modal
+const (
+	TitleBlock     = "notion_title"
+	TitleAction    = "title"
+	ContentBlock   = "notion_content"
+	ContentAction  = "content"
+	DatabaseBlock  = "notion_database"
+	DatabaseAction = "database"
+	TagsBlock      = "notion_tags"
+	TagsAction     = "tags"
+	DueDateBlock   = "notion_due_date"
+	DueDateAction  = "due_date"
+	AssigneeBlock  = "notion_assignee"
+	AssigneeAction = "assignee"
+)
+
+// maxTitleLength is the limit of a Notion rich text, which holds the page title
+const maxTitleLength = 2000
+
+// Task is the submitted task modal
+type Task struct {
+	Title    string   `json:"title"`
+	Content  string   `json:"content"`
+	Database string   `json:"database"`
+	Tags     []string `json:"tags"`
+	// DueDate is "YYYY-MM-DD", or empty
+	DueDate string `json:"due_date"`
+	// Assignee is the Slack user ID, or empty
+	Assignee string `json:"assignee"`
+}
+
+// NewTaskModal returns the modal that adds a task to the Notion database
+func NewTaskModal(cfg *config.Config) *slack.ModalViewRequest {
+	titleInputLabel := plainText("タイトル")
+	titleInputElement := slack.NewPlainTextInputBlockElement(titleInputLabel, TitleAction)
+	titleInput := slack.NewInputBlock(TitleBlock, titleInputLabel, titleInputElement)
+
+	contentInputLabel := plainText("タスク内容")
+	contentInputElement := slack.NewPlainTextInputBlockElement(contentInputLabel, ContentAction)
+	contentInputElement.Multiline = true
+	contentInput := slack.NewInputBlock(ContentBlock, contentInputLabel, contentInputElement)
+	contentInput.Optional = true
+
+	blocks := []slack.Block{titleInput, contentInput}
+	if input := databaseInput(cfg.ModalDatabases(), ""); input != nil {
+		blocks = append(blocks, input)
+	}
+
+	if len(cfg.Modal.Tags) > 0 {
+		var options []*slack.OptionBlockObject
+		for _, tag := range cfg.Modal.Tags {
+			options = append(options, slack.NewOptionBlockObject(tag, plainText(tag), nil))
+		}
+		tagsLabel := plainText("タグ")
+		tagsInput := slack.NewInputBlock(TagsBlock, tagsLabel,
+			slack.NewOptionsMultiSelectBlockElement(slack.MultiOptTypeStatic, tagsLabel, TagsAction, options...))
+		tagsInput.Optional = true
+		blocks = append(blocks, tagsInput)
+	}
+
+	dueDateInput := slack.NewInputBlock(DueDateBlock, plainText("期限"), slack.NewDatePickerBlockElement(DueDateAction))
+	dueDateInput.Optional = true
+	blocks = append(blocks, dueDateInput)
+
+	// The assignee is written as the Notion user mapped from the Slack user
+	if len(cfg.NotionUsers) > 0 {
+		assigneeLabel := plainText("担当者")
+		assigneeInput := slack.NewInputBlock(AssigneeBlock, assigneeLabel,
+			slack.NewOptionsSelectBlockElement(slack.OptTypeUser, assigneeLabel, AssigneeAction))
+		assigneeInput.Optional = true
+		blocks = append(blocks, assigneeInput)
+	}
+
+	return &slack.ModalViewRequest{
+		Type:       slack.ViewType("modal"),
+		CallbackID: CallbackTask,
+		Title:      plainText("NotionのDBに追加する"),
+		Blocks:     slack.Blocks{BlockSet: blocks},
+		Close:      plainText("キャンセル"),
+		Submit:     plainText("追加"),
+	}
+}
+
+// NewTaskProgressModal returns the modal that replaces the submitted task modal while the task is added
+func NewTaskProgressModal() *slack.ModalViewRequest {
+	return NewTaskResultModal("Notionに追加しています…")
+}
+
+// NewTaskResultModal returns the modal that tells whether the task has been added
+func NewTaskResultModal(message string) *slack.ModalViewRequest {
+	return &slack.ModalViewRequest{
+		Type:  slack.ViewType("modal"),
+		Title: plainText("NotionのDBに追加する"),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewSectionBlock(plainText(truncate(message, maxInputLength)), nil, nil),
+		}},
+		Close: plainText("閉じる"),
+	}
+}
+
+// ParseTask reads the answers of the task modal
+func ParseTask(state *slack.ViewState) Task {
+	if state == nil {
+		return Task{}
+	}
+	values := state.Values
+
+	task := Task{
+		Title:    values[TitleBlock][TitleAction].Value,
+		Content:  values[ContentBlock][ContentAction].Value,
+		Database: values[DatabaseBlock][DatabaseAction].SelectedOption.Value,
+		DueDate:  values[DueDateBlock][DueDateAction].SelectedDate,
+		Assignee: values[AssigneeBlock][AssigneeAction].SelectedUser,
+	}
+	for _, option := range values[TagsBlock][TagsAction].SelectedOptions {
+		task.Tags = append(task.Tags, option.Value)
+	}
+	return task
+}
+
+// ValidateTask returns the error messages of the task keyed by block ID, or nil when the task is valid
+func ValidateTask(task Task, databases []config.ModalDatabase) map[string]string {
+	errors := map[string]string{}
+	validateTitle(errors, task.Title)
+	validateDatabase(errors, task.Database, databases)
+	if task.DueDate != "" {
+		if _, err := time.Parse("2006-01-02", task.DueDate); err != nil {
+			errors[DueDateBlock] = "日付の形式が正しくありません"
+		}
+	}
+	if len(errors) == 0 {
+		return nil
+	}
+	return errors
+}
+
+func validateTitle(errors map[string]string, title string) {
+	switch {
+	case strings.TrimSpace(title) == "":
+		errors[TitleBlock] = "タイトルを入力してください"
+	case utf8.RuneCountInString(title) > maxTitleLength:
+		errors[TitleBlock] = fmt.Sprintf("タイトルは%d文字以内にしてください", maxTitleLength)
+	}
+}
+
+func validateDatabase(errors map[string]string, database string, databases []config.ModalDatabase) {
+	if database == "" {
+		return
+	}
+	for _, db := range databases {
+		if db.ID == database {
+			return
+		}
+	}
+	errors[DatabaseBlock] = "データベースを選択し直してください"
+}
+
+// databaseInput returns the database picker with initial selected, or nil when there is nothing to choose
+func databaseInput(databases []config.ModalDatabase, initial string) *slack.InputBlock {
+	if len(databases) == 0 {
+		return nil
+	}
+
+	var options []*slack.OptionBlockObject
+	var initialOption *slack.OptionBlockObject
+	for _, db := range databases {
+		option := slack.NewOptionBlockObject(db.ID, plainText(db.Name), nil)
+		options = append(options, option)
+		if db.ID == initial || initialOption == nil {
+			initialOption = option
+		}
+	}
+
+	label := plainText("データベース")
+	element := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, label, DatabaseAction, options...)
+	element.InitialOption = initialOption
+	return slack.NewInputBlock(DatabaseBlock, label, element)
+}
+
+func plainText(text string) *slack.TextBlockObject {
+	return slack.NewTextBlockObject("plain_text", text, true, false)
+}
@@ -0,0 +1,100 @@
+package modal_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/modal"
+	"github.com/slack-go/slack"
+)
+
+func TestTaskModalRoundTrip(t *testing.T) {
+	cfg := &config.Config{
+		NotionDatabase: "db-1",
+		NotionUsers:    map[string]string{"alice@example.com": "notion-alice"},
+		Modal: config.Modal{
+			Databases: []config.ModalDatabase{{ID: "db-1", Name: "Tasks"}, {ID: "db-2", Name: "Bugs"}},
+			Tags:      []string{"bug", "urgent"},
+		},
+	}
+
+	answers := map[string]slack.BlockAction{
+		modal.TitleBlock:    {Value: "task"},
+		modal.ContentBlock:  {Value: "content"},
+		modal.DatabaseBlock: {SelectedOption: slack.OptionBlockObject{Value: "db-2"}},
+		modal.TagsBlock: {SelectedOptions: []slack.OptionBlockObject{
+			{Value: "bug"},
+			{Value: "urgent"},
+		}},
+		modal.DueDateBlock:  {SelectedDate: "2023-04-01"},
+		modal.AssigneeBlock: {SelectedUser: "U1"},
+	}
+	want := modal.Task{
+		Title:    "task",
+		Content:  "content",
+		Database: "db-2",
+		Tags:     []string{"bug", "urgent"},
+		DueDate:  "2023-04-01",
+		Assignee: "U1",
+	}
+
+	task := modal.ParseTask(submit(t, modal.NewTaskModal(cfg), answers))
+	if !reflect.DeepEqual(task, want) {
+		t.Errorf("ParseTask = %+v, want %+v", task, want)
+	}
+	if errors := modal.ValidateTask(task, cfg.ModalDatabases()); errors != nil {
+		t.Errorf("ValidateTask = %v, want nil", errors)
+	}
+}
+
+func TestNewTaskModalHidesUnconfiguredFields(t *testing.T) {
+	request := modal.NewTaskModal(&config.Config{})
+
+	var blockIDs []string
+	for _, block := range request.Blocks.BlockSet {
+		blockIDs = append(blockIDs, block.(*slack.InputBlock).BlockID)
+	}
+	want := []string{modal.TitleBlock, modal.ContentBlock, modal.DueDateBlock}
+	if !reflect.DeepEqual(blockIDs, want) {
+		t.Errorf("blocks = %v, want %v", blockIDs, want)
+	}
+}
+
+func TestValidateTask(t *testing.T) {
+	databases := []config.ModalDatabase{{ID: "db-1"}, {ID: "db-2"}}
+
+	tests := []struct {
+		name string
+		task modal.Task
+		// wantErrors are the block IDs with an error
+		wantErrors []string
+	}{
+		{name: "title only", task: modal.Task{Title: "task"}},
+		{name: "every field", task: modal.Task{Title: "task", Database: "db-2", DueDate: "2023-04-01"}},
+		{name: "blank title", task: modal.Task{Title: " \n"}, wantErrors: []string{modal.TitleBlock}},
+		{name: "title too long", task: modal.Task{Title: strings.Repeat("あ", 2001)}, wantErrors: []string{modal.TitleBlock}},
+		{name: "unknown database", task: modal.Task{Title: "task", Database: "db-3"}, wantErrors: []string{modal.DatabaseBlock}},
+		{name: "invalid due date", task: modal.Task{Title: "task", DueDate: "2023/04/01"}, wantErrors: []string{modal.DueDateBlock}},
+		{
+			name:       "every field invalid",
+			task:       modal.Task{Database: "db-3", DueDate: "tomorrow"},
+			wantErrors: []string{modal.TitleBlock, modal.DatabaseBlock, modal.DueDateBlock},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := modal.ValidateTask(tt.task, databases)
+			if len(errors) != len(tt.wantErrors) {
+				t.Fatalf("ValidateTask = %v, want errors in %v", errors, tt.wantErrors)
+			}
+			for _, blockID := range tt.wantErrors {
+				if errors[blockID] == "" {
+					t.Errorf("ValidateTask = %v, want an error in %s", errors, blockID)
+				}
+			}
+		})
+	}
+}
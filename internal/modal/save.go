@@ -0,0 +1,135 @@
+package modal
+
+import (
+	"encoding/json"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/slack-go/slack"
+)
+
+// Callback IDs of the "Save to Notion" message shortcut and its modal
+const (
+	CallbackSaveShortcut = "save_to_notion"
+	CallbackSave         = "save_to_notion_modal"
+)
+
+// Block and action IDs of the save modal that are not shared with the task modal
+const (
+	SummaryBlock  = "notion_summary"
+	SummaryAction = "summary"
+)
+
+// maxInputLength is the limit of the initial value of a plain_text_input
+const maxInputLength = 3000
+
+// SaveMetadata is kept in the private_metadata of the save modal
+type SaveMetadata struct {
+	Channel         string `json:"channel"`
+	ThreadTimestamp string `json:"thread_ts"`
+	User            string `json:"user"`
+}
+
+// Save is the submitted save modal
+type Save struct {
+	SaveMetadata
+	Title    string
+	Summary  string
+	Database string
+}
+
+// NewSaveLoadingModal returns the modal shown while the title and summary are generated
+func NewSaveLoadingModal(metadata SaveMetadata) *slack.ModalViewRequest {
+	return &slack.ModalViewRequest{
+		Type:            slack.ViewType("modal"),
+		CallbackID:      CallbackSave,
+		PrivateMetadata: encodeMetadata(metadata),
+		Title:           plainText("Notionに保存する"),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewSectionBlock(plainText("タイトルと要約を生成しています…"), nil, nil),
+		}},
+		Close: plainText("キャンセル"),
+	}
+}
+
+// NewSaveErrorModal returns the modal shown when the title and summary could not be generated
+func NewSaveErrorModal(message string) *slack.ModalViewRequest {
+	return &slack.ModalViewRequest{
+		Type:  slack.ViewType("modal"),
+		Title: plainText("Notionに保存する"),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewSectionBlock(plainText(truncate("スレッドを読み込めませんでした: "+message, maxInputLength)), nil, nil),
+		}},
+		Close: plainText("閉じる"),
+	}
+}
+
+// NewSaveModal returns the modal to review the generated title and summary before saving the thread.
+// The database picker lists the databases routed from the channel of the thread
+func NewSaveModal(cfg *config.Config, metadata SaveMetadata, database string, title string, summary string) *slack.ModalViewRequest {
+	titleInputLabel := plainText("タイトル")
+	titleInputElement := slack.NewPlainTextInputBlockElement(titleInputLabel, TitleAction)
+	titleInputElement.InitialValue = truncate(title, maxInputLength)
+	titleInput := slack.NewInputBlock(TitleBlock, titleInputLabel, titleInputElement)
+
+	summaryInputLabel := plainText("要約")
+	summaryInputElement := slack.NewPlainTextInputBlockElement(summaryInputLabel, SummaryAction)
+	summaryInputElement.Multiline = true
+	summaryInputElement.InitialValue = truncate(summary, maxInputLength)
+	summaryInput := slack.NewInputBlock(SummaryBlock, summaryInputLabel, summaryInputElement)
+	summaryInput.Optional = true
+
+	blocks := []slack.Block{titleInput, summaryInput}
+	if input := databaseInput(cfg.SaveDatabases(metadata.Channel), database); input != nil {
+		blocks = append(blocks, input)
+	}
+
+	return &slack.ModalViewRequest{
+		Type:            slack.ViewType("modal"),
+		CallbackID:      CallbackSave,
+		PrivateMetadata: encodeMetadata(metadata),
+		Title:           plainText("Notionに保存する"),
+		Blocks:          slack.Blocks{BlockSet: blocks},
+		Close:           plainText("キャンセル"),
+		Submit:          plainText("保存"),
+	}
+}
+
+// ParseSave reads the answers and the metadata of the submitted save modal
+func ParseSave(view slack.View) (Save, error) {
+	var save Save
+	if err := json.Unmarshal([]byte(view.PrivateMetadata), &save.SaveMetadata); err != nil {
+		return Save{}, err
+	}
+	if view.State != nil {
+		values := view.State.Values
+		save.Title = values[TitleBlock][TitleAction].Value
+		save.Summary = values[SummaryBlock][SummaryAction].Value
+		save.Database = values[DatabaseBlock][DatabaseAction].SelectedOption.Value
+	}
+	return save, nil
+}
+
+// ValidateSave returns the error messages of the save modal keyed by block ID, or nil when it is valid
+func ValidateSave(save Save, databases []config.ModalDatabase) map[string]string {
+	errors := map[string]string{}
+	validateTitle(errors, save.Title)
+	validateDatabase(errors, save.Database, databases)
+	if len(errors) == 0 {
+		return nil
+	}
+	return errors
+}
+
+func encodeMetadata(metadata SaveMetadata) string {
+	b, _ := json.Marshal(metadata)
+	return string(b)
+}
+
+// truncate shortens text to limit characters
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit])
+}
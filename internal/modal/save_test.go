@@ -0,0 +1,126 @@
+package modal_test
+
+import (
+	"testing"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/modal"
+	"github.com/slack-go/slack"
+)
+
+// submit returns the state of request submitted with the answers keyed by block ID.
+// The action IDs are read from the input blocks of request, so that answers to blocks missing from it fail the test
+func submit(t *testing.T, request *slack.ModalViewRequest, answers map[string]slack.BlockAction) *slack.ViewState {
+	t.Helper()
+	state := &slack.ViewState{Values: map[string]map[string]slack.BlockAction{}}
+	for _, block := range request.Blocks.BlockSet {
+		input, ok := block.(*slack.InputBlock)
+		if !ok {
+			continue
+		}
+		answer, ok := answers[input.BlockID]
+		if !ok {
+			continue
+		}
+
+		var actionID string
+		switch e := input.Element.(type) {
+		case *slack.PlainTextInputBlockElement:
+			actionID = e.ActionID
+		case *slack.SelectBlockElement:
+			actionID = e.ActionID
+		case *slack.MultiSelectBlockElement:
+			actionID = e.ActionID
+		case *slack.DatePickerBlockElement:
+			actionID = e.ActionID
+		default:
+			t.Fatalf("unexpected element %T in block %s", input.Element, input.BlockID)
+		}
+		state.Values[input.BlockID] = map[string]slack.BlockAction{actionID: answer}
+	}
+	for blockID := range answers {
+		if _, ok := state.Values[blockID]; !ok {
+			t.Fatalf("block %s is not in the modal", blockID)
+		}
+	}
+	return state
+}
+
+func TestSaveModalRoundTrip(t *testing.T) {
+	cfg := &config.Config{Routes: []config.Route{
+		{Reaction: "memo", Database: "db-1"},
+		{Reaction: "bug", Database: "db-2", Channels: []string{"C1"}},
+		{Reaction: "todo", Database: "db-3", Channels: []string{"C2"}},
+	}}
+	metadata := modal.SaveMetadata{Channel: "C1", ThreadTimestamp: "1680000000.000100", User: "U1"}
+
+	tests := []struct {
+		name    string
+		answers map[string]slack.BlockAction
+		want    modal.Save
+		// wantErrors are the block IDs with an error
+		wantErrors []string
+	}{
+		{
+			name: "edited title and summary",
+			answers: map[string]slack.BlockAction{
+				modal.TitleBlock:    {Value: "edited title"},
+				modal.SummaryBlock:  {Value: "edited summary"},
+				modal.DatabaseBlock: {SelectedOption: slack.OptionBlockObject{Value: "db-2"}},
+			},
+			want: modal.Save{SaveMetadata: metadata, Title: "edited title", Summary: "edited summary", Database: "db-2"},
+		},
+		{
+			name: "blank title",
+			answers: map[string]slack.BlockAction{
+				modal.TitleBlock: {Value: " "},
+			},
+			want:       modal.Save{SaveMetadata: metadata, Title: " "},
+			wantErrors: []string{modal.TitleBlock},
+		},
+		{
+			name: "database not routed from the channel",
+			answers: map[string]slack.BlockAction{
+				modal.TitleBlock:    {Value: "title"},
+				modal.DatabaseBlock: {SelectedOption: slack.OptionBlockObject{Value: "db-3"}},
+			},
+			want:       modal.Save{SaveMetadata: metadata, Title: "title", Database: "db-3"},
+			wantErrors: []string{modal.DatabaseBlock},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := modal.NewSaveModal(cfg, metadata, "db-1", "generated title", "generated summary")
+			view := slack.View{
+				CallbackID:      request.CallbackID,
+				PrivateMetadata: request.PrivateMetadata,
+				State:           submit(t, request, tt.answers),
+			}
+
+			save, err := modal.ParseSave(view)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if save != tt.want {
+				t.Errorf("ParseSave = %+v, want %+v", save, tt.want)
+			}
+
+			errors := modal.ValidateSave(save, cfg.SaveDatabases(save.Channel))
+			if len(errors) != len(tt.wantErrors) {
+				t.Fatalf("ValidateSave = %v, want errors in %v", errors, tt.wantErrors)
+			}
+			for _, blockID := range tt.wantErrors {
+				if errors[blockID] == "" {
+					t.Errorf("ValidateSave = %v, want an error in %s", errors, blockID)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSaveInvalidMetadata(t *testing.T) {
+	if _, err := modal.ParseSave(slack.View{PrivateMetadata: "{"}); err == nil {
+		t.Error("err = nil, want an invalid metadata error")
+	}
+}
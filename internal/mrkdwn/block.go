@@ -0,0 +1,161 @@
+package mrkdwn
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+var (
+	bulletLine   = regexp.MustCompile(`^(\s*)([•◦▪\-*])\s+(.*)$`)
+	numberedLine = regexp.MustCompile(`^(\s*)\d+[.)]\s+(.*)$`)
+)
+
+// ParseBlocks converts text into native Notion blocks: ```code fences``` become Code blocks,
+// &gt; quotes become Quote blocks, "•"/"-" and "1." lines become list items (indented items are
+// nested in the previous item) and the remaining lines become Paragraph blocks.
+func (p *Parser) ParseBlocks(text string) []notion.Block {
+	var blocks []notion.Block
+	for text != "" {
+		start := strings.Index(text, "```")
+		if start == -1 {
+			blocks = append(blocks, p.parseLines(text)...)
+			break
+		}
+		end := strings.Index(text[start+3:], "```")
+		if end == -1 {
+			blocks = append(blocks, p.parseLines(text)...)
+			break
+		}
+
+		blocks = append(blocks, p.parseLines(text[:start])...)
+		blocks = append(blocks, codeBlock(Unescape(text[start+3:start+3+end])))
+		text = text[start+3+end+3:]
+	}
+	return blocks
+}
+
+func (p *Parser) parseLines(text string) []notion.Block {
+	var (
+		blocks    []notion.Block
+		paragraph []string
+		quote     []string
+	)
+
+	flush := func() {
+		if len(paragraph) != 0 {
+			blocks = append(blocks, notion.Block{
+				Object:    "block",
+				Type:      notion.BlockTypeParagraph,
+				Paragraph: &notion.RichTextBlock{Text: p.Parse(strings.TrimSpace(strings.Join(paragraph, "\n")))},
+			})
+			paragraph = nil
+		}
+		if len(quote) != 0 {
+			blocks = append(blocks, notion.Block{
+				Object: "block",
+				Type:   notion.BlockTypeQuote,
+				Quote:  &notion.RichTextBlock{Text: p.Parse(strings.Join(quote, "\n"))},
+			})
+			quote = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if body, ok := quoteLine(line); ok {
+			if len(paragraph) != 0 {
+				flush()
+			}
+			quote = append(quote, body)
+			continue
+		}
+
+		if m := bulletLine.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = appendListItem(blocks, p.listItem(notion.BlockTypeBulletedListItem, m[3]), isNested(m[1], m[2]))
+			continue
+		}
+		if m := numberedLine.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = appendListItem(blocks, p.listItem(notion.BlockTypeNumberedListItem, m[2]), isNested(m[1], ""))
+			continue
+		}
+
+		if len(quote) != 0 {
+			flush()
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	flush()
+
+	return blocks
+}
+
+func (p *Parser) listItem(typ notion.BlockType, text string) notion.Block {
+	item := &notion.RichTextBlock{Text: p.Parse(text)}
+	block := notion.Block{Object: "block", Type: typ}
+	if typ == notion.BlockTypeNumberedListItem {
+		block.NumberedListItem = item
+	} else {
+		block.BulletedListItem = item
+	}
+	return block
+}
+
+// appendListItem appends item, nesting it in the previous list item when nested is true
+func appendListItem(blocks []notion.Block, item notion.Block, nested bool) []notion.Block {
+	if nested && len(blocks) != 0 {
+		last := &blocks[len(blocks)-1]
+		var parent *notion.RichTextBlock
+		switch last.Type {
+		case notion.BlockTypeBulletedListItem:
+			parent = last.BulletedListItem
+		case notion.BlockTypeNumberedListItem:
+			parent = last.NumberedListItem
+		}
+		if parent != nil {
+			parent.Children = append(parent.Children, item)
+			return blocks
+		}
+	}
+	return append(blocks, item)
+}
+
+// isNested reports whether a list item is indented. Slack renders second level bullets as "◦"
+func isNested(indent string, marker string) bool {
+	return len(indent) >= 2 || marker == "◦" || marker == "▪"
+}
+
+// quoteLine returns the body of a "&gt; quote" line. Slack escapes ">" in message text
+func quoteLine(line string) (string, bool) {
+	for _, prefix := range []string{"&gt; ", "&gt;", "> "} {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix), true
+		}
+	}
+	return "", false
+}
+
+func codeBlock(code string) notion.Block {
+	language, body := DetectLanguage(code)
+	return notion.Block{
+		Object: "block",
+		Type:   notion.BlockTypeCode,
+		Code: &notion.Code{
+			RichTextBlock: notion.RichTextBlock{
+				Text: []notion.RichText{
+					{
+						Type: notion.RichTextTypeText,
+						Text: &notion.Text{Content: body},
+					},
+				},
+			},
+			Language: &language,
+		},
+	}
+}
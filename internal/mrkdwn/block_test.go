@@ -0,0 +1,151 @@
+package mrkdwn_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/mrkdwn"
+)
+
+// describeBlocks renders every block as "<type>: <plain text>", with nested blocks indented
+func describeBlocks(blocks []notion.Block, indent string) []string {
+	var described []string
+	for _, b := range blocks {
+		var (
+			name string
+			rtb  *notion.RichTextBlock
+		)
+		switch b.Type {
+		case notion.BlockTypeParagraph:
+			name, rtb = "paragraph", b.Paragraph
+		case notion.BlockTypeQuote:
+			name, rtb = "quote", b.Quote
+		case notion.BlockTypeBulletedListItem:
+			name, rtb = "bullet", b.BulletedListItem
+		case notion.BlockTypeNumberedListItem:
+			name, rtb = "number", b.NumberedListItem
+		case notion.BlockTypeCode:
+			name, rtb = "code("+*b.Code.Language+")", &b.Code.RichTextBlock
+		}
+		described = append(described, indent+name+": "+mrkdwn.PlainText(rtb.Text))
+		described = append(described, describeBlocks(rtb.Children, indent+"  ")...)
+	}
+	return described
+}
+
+func TestParseBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "paragraphs",
+			text: "first line\nsecond line\n\nnext paragraph",
+			want: []string{"paragraph: first line\nsecond line", "paragraph: next paragraph"},
+		},
+		{
+			name: "quote",
+			text: "&gt; quoted\n&gt; more\nafter",
+			want: []string{"quote: quoted\nmore", "paragraph: after"},
+		},
+		{
+			name: "quote after a paragraph",
+			text: "before\n&gt;quoted",
+			want: []string{"paragraph: before", "quote: quoted"},
+		},
+		{
+			name: "bullets",
+			text: "• one\n• *two*",
+			want: []string{"bullet: one", "bullet: two"},
+		},
+		{
+			name: "indented bullets are nested",
+			text: "- parent\n  - child\n- sibling",
+			want: []string{"bullet: parent", "  bullet: child", "bullet: sibling"},
+		},
+		{
+			name: "second level bullet marker is nested",
+			text: "• parent\n◦ child",
+			want: []string{"bullet: parent", "  bullet: child"},
+		},
+		{
+			name: "indented item without a parent",
+			text: "intro\n  - item",
+			want: []string{"paragraph: intro", "bullet: item"},
+		},
+		{
+			name: "numbered",
+			text: "1. one\n2) two",
+			want: []string{"number: one", "number: two"},
+		},
+		{
+			name: "bold line is not a bullet",
+			text: "*bold*",
+			want: []string{"paragraph: bold"},
+		},
+		{
+			name: "code fence",
+			text: "before\n```go\nfmt.Println(\"*x*\")\n```\nafter",
+			want: []string{"paragraph: before", "code(go): fmt.Println(\"*x*\")", "paragraph: after"},
+		},
+		{
+			name: "code fence is unescaped",
+			text: "```a &lt; b```",
+			want: []string{"code(plain text): a < b"},
+		},
+		{
+			name: "unclosed code fence",
+			text: "```not closed",
+			want: []string{"paragraph: ```not closed"},
+		},
+		{
+			name: "two code fences",
+			text: "```one```\n```two```",
+			want: []string{"code(plain text): one", "code(plain text): two"},
+		},
+		{
+			name: "empty",
+			text: "",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeBlocks((&mrkdwn.Parser{}).ParseBlocks(tt.text), "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBlocks(%q) =\n%s\nwant\n%s", tt.text, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		language string
+		body     string
+	}{
+		{name: "language line", code: "\npython\nprint(1)\n", language: "python", body: "print(1)"},
+		{name: "language alias", code: "ts\nlet x = 1", language: "typescript", body: "let x = 1"},
+		{name: "json", code: `{"a": 1}`, language: "json", body: `{"a": 1}`},
+		{name: "go", code: "x := 1", language: "go", body: "x := 1"},
+		{name: "sql", code: "SELECT * FROM users", language: "sql", body: "SELECT * FROM users"},
+		{name: "shell", code: "$ make build", language: "shell", body: "$ make build"},
+		{name: "unknown", code: "just text", language: mrkdwn.LanguagePlainText, body: "just text"},
+		{name: "unknown first line is kept", code: "hello\nworld", language: mrkdwn.LanguagePlainText, body: "hello\nworld"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			language, body := mrkdwn.DetectLanguage(tt.code)
+			if language != tt.language || body != tt.body {
+				t.Errorf("DetectLanguage(%q) = %q, %q, want %q, %q", tt.code, language, body, tt.language, tt.body)
+			}
+		})
+	}
+}
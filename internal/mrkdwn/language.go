@@ -0,0 +1,61 @@
+package mrkdwn
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// LanguagePlainText is the Notion code language used when the language cannot be detected
+const LanguagePlainText = "plain text"
+
+// languageNames maps the names people put on the first line of a fence to Notion code languages
+var languageNames = map[string]string{
+	"bash": "bash", "sh": "shell", "shell": "shell", "zsh": "shell", "console": "shell",
+	"c": "c", "cpp": "c++", "c++": "c++", "cs": "c#", "csharp": "c#",
+	"css": "css", "diff": "diff", "docker": "docker", "dockerfile": "docker",
+	"go": "go", "golang": "go", "graphql": "graphql", "html": "html",
+	"java": "java", "javascript": "javascript", "js": "javascript", "json": "json",
+	"kotlin": "kotlin", "kt": "kotlin", "makefile": "makefile", "markdown": "markdown", "md": "markdown",
+	"php": "php", "python": "python", "py": "python", "ruby": "ruby", "rb": "ruby",
+	"rust": "rust", "rs": "rust", "scala": "scala", "sql": "sql", "swift": "swift",
+	"typescript": "typescript", "ts": "typescript", "xml": "xml", "yaml": "yaml", "yml": "yaml",
+	"text": LanguagePlainText, "txt": LanguagePlainText, "plaintext": LanguagePlainText,
+}
+
+// languagePatterns are checked in order against the code when no language name is given
+var languagePatterns = []struct {
+	language string
+	pattern  *regexp.Regexp
+}{
+	{"go", regexp.MustCompile(`(?m)^package \w+$|\bfunc (\(\w+ \*?\w+\) )?\w+\(|:= `)},
+	{"python", regexp.MustCompile(`(?m)^\s*(def \w+\(.*\):|from [\w.]+ import |import \w+$|class \w+(\(.*\))?:$)|Traceback \(most recent call last\)`)},
+	{"sql", regexp.MustCompile(`(?i)^\s*(select .+ from |insert into |update \w+ set |delete from |create table |alter table )`)},
+	{"shell", regexp.MustCompile(`(?m)^(#!/bin/(ba)?sh|\$ \w+)`)},
+	{"html", regexp.MustCompile(`(?i)^\s*(<!doctype html|<html|<div|<body)`)},
+	{"typescript", regexp.MustCompile(`\b(interface \w+ \{|: (string|number|boolean)\b)`)},
+	{"javascript", regexp.MustCompile(`\b(const|let) \w+ = |\bfunction \w*\(|=> \{|console\.log\(`)},
+}
+
+// DetectLanguage returns the Notion code language of the fenced code and the code without
+// a leading language line such as "go" or "python"
+func DetectLanguage(code string) (string, string) {
+	code = strings.Trim(code, "\n")
+
+	if first, rest, ok := strings.Cut(code, "\n"); ok {
+		if language, known := languageNames[strings.ToLower(strings.TrimSpace(first))]; known {
+			return language, rest
+		}
+	}
+
+	trimmed := strings.TrimSpace(code)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json", code
+	}
+	for _, lp := range languagePatterns {
+		if lp.pattern.MatchString(code) {
+			return lp.language, code
+		}
+	}
+	return LanguagePlainText, code
+}
@@ -0,0 +1,322 @@
+// Package mrkdwn converts Slack mrkdwn into Notion rich text.
+// https://api.slack.com/reference/surfaces/formatting
+package mrkdwn
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// MentionKind is the kind of a special token in angle brackets
+type MentionKind string
+
+// Kinds of the special tokens
+const (
+	MentionUser      MentionKind = "user"      // <@U012ABC>
+	MentionChannel   MentionKind = "channel"   // <#C0123|general>
+	MentionUsergroup MentionKind = "usergroup" // <!subteam^S123|@team>
+	MentionSpecial   MentionKind = "special"   // <!here>, <!channel>, <!everyone>, <!date^...|fallback>
+)
+
+// Mention is a parsed <@…>, <#…> or <!…> token
+type Mention struct {
+	Kind MentionKind
+	// ID is the user, channel or usergroup ID, or the keyword of a special mention
+	ID string
+	// Label is the text after "|", if any
+	Label string
+}
+
+// MentionResolver renders a mention as Notion rich text. The parser applies the surrounding annotations.
+type MentionResolver interface {
+	ResolveMention(m Mention) notion.RichText
+}
+
+// Parser converts Slack mrkdwn to Notion rich text
+type Parser struct {
+	// Mentions renders mentions. Defaults to PlainMentions
+	Mentions MentionResolver
+}
+
+// Parse converts *bold*, _italic_, ~strike~, `code`, <url|label> and mentions in text
+// into Notion rich text with annotations and links
+func (p *Parser) Parse(text string) []notion.RichText {
+	var b builder
+	p.parseInline(&b, text, style{})
+	return b.rich
+}
+
+type style struct {
+	bold, italic, strike, code bool
+}
+
+func (s style) with(marker byte) style {
+	switch marker {
+	case '*':
+		s.bold = true
+	case '_':
+		s.italic = true
+	case '~':
+		s.strike = true
+	}
+	return s
+}
+
+func (s style) annotations() *notion.Annotations {
+	if s == (style{}) {
+		return nil
+	}
+	return &notion.Annotations{
+		Bold:          s.bold,
+		Italic:        s.italic,
+		Strikethrough: s.strike,
+		Code:          s.code,
+		Color:         notion.ColorDefault,
+	}
+}
+
+func (p *Parser) parseInline(b *builder, text string, st style) {
+	var plain strings.Builder
+	flush := func() {
+		if plain.Len() != 0 {
+			b.addText(Unescape(plain.String()), st, "")
+			plain.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		switch c := text[i]; c {
+		case '`':
+			if end := closingIndex(text, i+1, '`'); end > i+1 {
+				flush()
+				code := st
+				code.code = true
+				b.addText(Unescape(text[i+1:end]), code, "")
+				i = end + 1
+				continue
+			}
+		case '<':
+			if end := closingIndex(text, i+1, '>'); end > i+1 {
+				flush()
+				p.parseAngle(b, text[i+1:end], st)
+				i = end + 1
+				continue
+			}
+		case '*', '_', '~':
+			if isOpener(text, i) {
+				if end := findCloser(text, i); end != -1 {
+					flush()
+					p.parseInline(b, text[i+1:end], st.with(c))
+					i = end + 1
+					continue
+				}
+			}
+		}
+		plain.WriteByte(text[i])
+		i++
+	}
+	flush()
+}
+
+// parseAngle renders the inside of <…>: links and mentions
+func (p *Parser) parseAngle(b *builder, token string, st style) {
+	target, label, _ := strings.Cut(token, "|")
+
+	var m *Mention
+	switch {
+	case strings.HasPrefix(target, "@"):
+		m = &Mention{Kind: MentionUser, ID: target[1:], Label: label}
+	case strings.HasPrefix(target, "#"):
+		m = &Mention{Kind: MentionChannel, ID: target[1:], Label: label}
+	case strings.HasPrefix(target, "!subteam^"):
+		m = &Mention{Kind: MentionUsergroup, ID: strings.TrimPrefix(target, "!subteam^"), Label: label}
+	case strings.HasPrefix(target, "!"):
+		m = &Mention{Kind: MentionSpecial, ID: target[1:], Label: label}
+	}
+	if m != nil {
+		m.Label = Unescape(m.Label)
+		b.add(p.mentions().ResolveMention(*m), st)
+		return
+	}
+
+	url := Unescape(target)
+	if label == "" {
+		label = strings.TrimPrefix(url, "mailto:")
+	}
+	b.addText(Unescape(label), st, url)
+}
+
+func (p *Parser) mentions() MentionResolver {
+	if p.Mentions != nil {
+		return p.Mentions
+	}
+	return PlainMentions{}
+}
+
+// PlainMentions renders mentions as plain text using the label in the token when present
+type PlainMentions struct{}
+
+// ResolveMention renders "@U012ABC", "#general", "@team" or "@here"
+func (PlainMentions) ResolveMention(m Mention) notion.RichText {
+	return notion.RichText{
+		Type: notion.RichTextTypeText,
+		Text: &notion.Text{Content: MentionText(m, "")},
+	}
+}
+
+// MentionText returns the readable text of the mention, preferring name over the label in the token
+func MentionText(m Mention, name string) string {
+	switch m.Kind {
+	case MentionUser:
+		return "@" + firstNonEmpty(name, strings.TrimPrefix(m.Label, "@"), m.ID)
+	case MentionChannel:
+		return "#" + firstNonEmpty(name, strings.TrimPrefix(m.Label, "#"), m.ID)
+	case MentionUsergroup:
+		return "@" + firstNonEmpty(name, strings.TrimPrefix(m.Label, "@"), m.ID)
+	default:
+		if m.Label != "" {
+			return m.Label
+		}
+		keyword, _, _ := strings.Cut(m.ID, "^")
+		return "@" + keyword
+	}
+}
+
+var unescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">")
+
+// Unescape decodes the three HTML entities that Slack escapes in message text
+func Unescape(text string) string {
+	return unescaper.Replace(text)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// closingIndex returns the index of the first c at or after from on the same line, or -1
+func closingIndex(text string, from int, c byte) int {
+	for j := from; j < len(text); j++ {
+		switch text[j] {
+		case c:
+			return j
+		case '\n':
+			return -1
+		}
+	}
+	return -1
+}
+
+// isOpener reports whether the marker at i can open a span: it follows a word boundary
+// and is followed by a non-space character
+func isOpener(text string, i int) bool {
+	if i+1 >= len(text) || isSpace(text[i+1]) || text[i+1] == text[i] {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRuneInString(text[:i])
+	return !isWordRune(prev)
+}
+
+// findCloser returns the index of the marker closing the span opened at i on the same line, or -1
+func findCloser(text string, i int) int {
+	marker := text[i]
+	for j := i + 2; j < len(text); j++ {
+		switch text[j] {
+		case '\n':
+			return -1
+		case '`', '<':
+			// Markers inside code and links do not close the span
+			closing := byte('`')
+			if text[j] == '<' {
+				closing = '>'
+			}
+			if end := closingIndex(text, j+1, closing); end != -1 {
+				j = end
+			}
+		case marker:
+			if isSpace(text[j-1]) {
+				continue
+			}
+			if j+1 < len(text) {
+				next, _ := utf8.DecodeRuneInString(text[j+1:])
+				if isWordRune(next) {
+					continue
+				}
+			}
+			return j
+		}
+	}
+	return -1
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// isWordRune reports whether r glues to a marker, so that snake_case and 2*3*4 stay plain text.
+// Non-ASCII letters do not, because Japanese text has no spaces between words.
+func isWordRune(r rune) bool {
+	return r < utf8.RuneSelf && (r == '_' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+}
+
+// builder accumulates rich text, merging adjacent text with the same annotations and link
+type builder struct {
+	rich []notion.RichText
+}
+
+func (b *builder) addText(content string, st style, url string) {
+	if content == "" {
+		return
+	}
+
+	text := &notion.Text{Content: content}
+	if url != "" {
+		text.Link = &notion.Link{URL: url}
+	}
+	b.add(notion.RichText{Type: notion.RichTextTypeText, Text: text}, st)
+}
+
+func (b *builder) add(rt notion.RichText, st style) {
+	if rt.Annotations == nil {
+		rt.Annotations = st.annotations()
+	}
+
+	if n := len(b.rich); n != 0 && rt.Text != nil && rt.Text.Link == nil {
+		last := &b.rich[n-1]
+		if last.Type == notion.RichTextTypeText && last.Text != nil && last.Text.Link == nil && sameAnnotations(last.Annotations, rt.Annotations) {
+			last.Text.Content += rt.Text.Content
+			return
+		}
+	}
+	b.rich = append(b.rich, rt)
+}
+
+func sameAnnotations(a, b *notion.Annotations) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// PlainText concatenates the text of the rich text, dropping annotations and links
+func PlainText(rich []notion.RichText) string {
+	var sb strings.Builder
+	for _, rt := range rich {
+		switch {
+		case rt.Text != nil:
+			sb.WriteString(rt.Text.Content)
+		case rt.PlainText != "":
+			sb.WriteString(rt.PlainText)
+		}
+	}
+	return sb.String()
+}
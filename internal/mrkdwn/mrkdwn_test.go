@@ -0,0 +1,119 @@
+package mrkdwn_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/mrkdwn"
+)
+
+// describe renders rich text as `<annotations>:"<content>"-><link>`, where the annotations are
+// b(old), i(talic), s(trike) and c(ode)
+func describe(rich []notion.RichText) []string {
+	var described []string
+	for _, rt := range rich {
+		var flags string
+		if a := rt.Annotations; a != nil {
+			for _, f := range []struct {
+				on   bool
+				flag string
+			}{{a.Bold, "b"}, {a.Italic, "i"}, {a.Strikethrough, "s"}, {a.Code, "c"}} {
+				if f.on {
+					flags += f.flag
+				}
+			}
+		}
+		s := fmt.Sprintf("%q", mrkdwn.PlainText([]notion.RichText{rt}))
+		if flags != "" {
+			s = flags + ":" + s
+		}
+		if rt.Text != nil && rt.Text.Link != nil {
+			s += "->" + rt.Text.Link.URL
+		}
+		described = append(described, s)
+	}
+	return described
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "plain", text: "hello", want: []string{`"hello"`}},
+		{name: "bold", text: "*bold* text", want: []string{`b:"bold"`, `" text"`}},
+		{name: "italic", text: "an _italic_ word", want: []string{`"an "`, `i:"italic"`, `" word"`}},
+		{name: "strike", text: "~gone~", want: []string{`s:"gone"`}},
+		{name: "code", text: "run `make`", want: []string{`"run "`, `c:"make"`}},
+		{name: "nested", text: "*bold _both_*", want: []string{`b:"bold "`, `bi:"both"`}},
+		{name: "markers in code are literal", text: "`*x*`", want: []string{`c:"*x*"`}},
+		{name: "code inside a span does not close it", text: "*a `b*` c*", want: []string{`b:"a "`, `bc:"b*"`, `b:" c"`}},
+		{name: "unclosed marker", text: "*not closed", want: []string{`"*not closed"`}},
+		{name: "unclosed code", text: "`not closed", want: []string{`"` + "`" + `not closed"`}},
+		{name: "opener followed by a space", text: "* not bold*", want: []string{`"* not bold*"`}},
+		{name: "closer preceded by a space", text: "*not bold *", want: []string{`"*not bold *"`}},
+		{name: "snake_case", text: "snake_case_name", want: []string{`"snake_case_name"`}},
+		{name: "arithmetic", text: "2*3*4", want: []string{`"2*3*4"`}},
+		{name: "doubled marker", text: "**", want: []string{`"**"`}},
+		{name: "span does not cross lines", text: "*a\nb*", want: []string{`"*a\nb*"`}},
+		{name: "japanese without spaces", text: "これは*太字*です", want: []string{`"これは"`, `b:"太字"`, `"です"`}},
+		{name: "link with label", text: "<https://example.com|Example>", want: []string{`"Example"->https://example.com`}},
+		{name: "bare link", text: "<https://example.com>", want: []string{`"https://example.com"->https://example.com`}},
+		{name: "mailto", text: "<mailto:a@example.com>", want: []string{`"a@example.com"->mailto:a@example.com`}},
+		{name: "bold link", text: "*<https://example.com|here>*", want: []string{`b:"here"->https://example.com`}},
+		{name: "escaped link", text: "<https://example.com/?a=1&amp;b=2|a &amp; b>", want: []string{`"a & b"->https://example.com/?a=1&b=2`}},
+		{name: "user mention", text: "hi <@U1>", want: []string{`"hi @U1"`}},
+		{name: "channel mention", text: "<#C1|general>", want: []string{`"#general"`}},
+		{name: "usergroup mention", text: "<!subteam^S1|@team>", want: []string{`"@team"`}},
+		{name: "special mention", text: "<!here>", want: []string{`"@here"`}},
+		{name: "date", text: "<!date^1680000000^{date}|Mar 28>", want: []string{`"Mar 28"`}},
+		{name: "bold mention", text: "*<@U1>*", want: []string{`b:"@U1"`}},
+		{name: "entities", text: "a &amp; b &lt;c&gt;", want: []string{`"a & b <c>"`}},
+		{name: "empty", text: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describe((&mrkdwn.Parser{}).Parse(tt.text))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// names resolves the mentions to fixed names
+type names map[string]string
+
+func (n names) ResolveMention(m mrkdwn.Mention) notion.RichText {
+	return notion.RichText{
+		Type: notion.RichTextTypeText,
+		Text: &notion.Text{Content: mrkdwn.MentionText(m, n[m.ID])},
+	}
+}
+
+func TestParseMentions(t *testing.T) {
+	p := &mrkdwn.Parser{Mentions: names{"U1": "alice", "C1": "general"}}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "<@U1> <@U2>", want: "@alice @U2"},
+		{text: "<@U2|bob>", want: "@bob"},
+		{text: "<#C1>", want: "#general"},
+		{text: "<!channel>", want: "@channel"},
+		{text: "_<@U1>_", want: "@alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := mrkdwn.PlainText(p.Parse(tt.text)); got != tt.want {
+				t.Errorf("Parse(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
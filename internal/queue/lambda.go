@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+// LambdaInvoke enqueues jobs by invoking a Lambda function asynchronously (InvocationType "Event").
+// The function receives the Job as its payload; use IsJob to tell it apart from API Gateway requests.
+type LambdaInvoke struct {
+	client       lambdaiface.LambdaAPI
+	functionName string
+}
+
+// NewLambdaInvoke returns a Queue that invokes functionName with the default AWS session
+func NewLambdaInvoke(functionName string) (*LambdaInvoke, error) {
+	if functionName == "" {
+		return nil, errors.New("lambda function name is empty")
+	}
+
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return &LambdaInvoke{client: lambda.New(sess), functionName: functionName}, nil
+}
+
+// Enqueue invokes the function with the job and returns without waiting for the result
+func (q *LambdaInvoke) Enqueue(ctx context.Context, job Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.client.InvokeWithContext(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(q.functionName),
+		InvocationType: aws.String(lambda.InvocationTypeEvent),
+		Payload:        payload,
+	})
+	return err
+}
+
+// IsJob decodes payload as a Job. It returns false for any other Lambda event
+func IsJob(payload []byte) (Job, bool) {
+	var job Job
+	if err := json.Unmarshal(payload, &job); err != nil || job.Kind == "" {
+		return Job{}, false
+	}
+	return job, true
+}
+
+// HTTPHandler handles an API Gateway request
+type HTTPHandler func(ctx context.Context, r events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// LambdaHandler returns the handler of a function that re-invokes itself with LambdaInvoke.
+// Jobs are processed by jobHandler and API Gateway requests from Slack by httpHandler.
+// Job failures are only logged, since Lambda would retry the whole job
+func LambdaHandler(jobHandler Handler, httpHandler HTTPHandler) func(ctx context.Context, payload json.RawMessage) (events.APIGatewayProxyResponse, error) {
+	return func(ctx context.Context, payload json.RawMessage) (events.APIGatewayProxyResponse, error) {
+		if job, ok := IsJob(payload); ok {
+			if err := jobHandler(ctx, job); err != nil {
+				log.Printf("[ERROR] Failed to process %s job: %v", job.Kind, err)
+			}
+			return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+		}
+
+		var r events.APIGatewayProxyRequest
+		if err := json.Unmarshal(payload, &r); err != nil {
+			log.Printf("[ERROR] Failed to unmarshal lambda payload: %v", err)
+			return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+		}
+		return httpHandler(ctx, r)
+	}
+}
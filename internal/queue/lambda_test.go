@@ -0,0 +1,91 @@
+package queue_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/queue"
+)
+
+func TestIsJob(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		wantKind string
+		wantOK   bool
+	}{
+		{name: "job", payload: `{"job_kind":"slack_event","payload":{"type":"event_callback"}}`, wantKind: queue.KindSlackEvent, wantOK: true},
+		{name: "API Gateway request", payload: `{"httpMethod":"POST","body":"{}"}`},
+		{name: "empty kind", payload: `{"job_kind":"","payload":{}}`},
+		{name: "not an object", payload: `"job"`},
+		{name: "invalid JSON", payload: `{`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, ok := queue.IsJob([]byte(tt.payload))
+			if ok != tt.wantOK || job.Kind != tt.wantKind {
+				t.Errorf("IsJob = %q, %v, want %q, %v", job.Kind, ok, tt.wantKind, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLambdaHandler(t *testing.T) {
+	jobErr := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		payload string
+		jobErr  error
+		// wantJob is the kind of the job handled, or empty when the job handler is not called
+		wantJob string
+		// wantBody is the body of the request handled, or empty when the HTTP handler is not called
+		wantBody string
+	}{
+		{name: "job", payload: `{"job_kind":"task","payload":{"title":"task"}}`, wantJob: queue.KindTask},
+		{name: "failed job", payload: `{"job_kind":"task","payload":{}}`, jobErr: jobErr, wantJob: queue.KindTask},
+		{name: "API Gateway request", payload: `{"httpMethod":"POST","headers":{"Content-Type":"application/json"},"body":"{\"type\":\"url_verification\"}"}`, wantBody: `{"type":"url_verification"}`},
+		{name: "unknown payload", payload: `[]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var job queue.Job
+			var request events.APIGatewayProxyRequest
+			handler := queue.LambdaHandler(
+				func(ctx context.Context, j queue.Job) error {
+					job = j
+					return tt.jobErr
+				},
+				func(ctx context.Context, r events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+					request = r
+					return events.APIGatewayProxyResponse{StatusCode: 202}, nil
+				},
+			)
+
+			res, err := handler(context.Background(), json.RawMessage(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Kind != tt.wantJob {
+				t.Errorf("job = %q, want %q", job.Kind, tt.wantJob)
+			}
+			if request.Body != tt.wantBody {
+				t.Errorf("request body = %q, want %q", request.Body, tt.wantBody)
+			}
+
+			// Only requests get the response of the HTTP handler; jobs and unknown payloads are acknowledged
+			wantStatus := 200
+			if tt.wantBody != "" {
+				wantStatus = 202
+			}
+			if res.StatusCode != wantStatus {
+				t.Errorf("StatusCode = %d, want %d", res.StatusCode, wantStatus)
+			}
+		})
+	}
+}
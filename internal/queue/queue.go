@@ -0,0 +1,66 @@
+// Package queue defers work until after the Slack request has been acknowledged.
+// Slack expects a response within 3 seconds, so the handlers only enqueue a Job
+// and the thread is archived afterwards.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// Job kinds
+const (
+	// KindSlackEvent is the Job kind whose payload is the raw Events API request body
+	KindSlackEvent = "slack_event"
+	// KindSavePreview generates the title and summary shown in the "Save to Notion" modal
+	KindSavePreview = "save_preview"
+	// KindSave archives the thread reviewed in the "Save to Notion" modal
+	KindSave = "save"
+	// KindTask adds the task submitted in the task modal to the Notion database
+	KindTask = "task"
+)
+
+// Job is a unit of work processed after the Slack request has been acknowledged
+type Job struct {
+	Kind    string          `json:"job_kind"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Handler processes a Job
+type Handler func(ctx context.Context, job Job) error
+
+// Queue accepts jobs to be processed asynchronously
+type Queue interface {
+	Enqueue(ctx context.Context, job Job) error
+}
+
+// InProcess runs every Job in its own goroutine of the current process
+type InProcess struct {
+	handler Handler
+	wg      sync.WaitGroup
+}
+
+// NewInProcess returns a Queue that runs jobs with handler in the current process
+func NewInProcess(handler Handler) *InProcess {
+	return &InProcess{handler: handler}
+}
+
+// Enqueue starts processing the job and returns immediately
+func (q *InProcess) Enqueue(ctx context.Context, job Job) error {
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		// The request context is canceled once the response is written
+		if err := q.handler(context.Background(), job); err != nil {
+			log.Printf("[ERROR] Failed to process %s job: %v", job.Kind, err)
+		}
+	}()
+	return nil
+}
+
+// Wait blocks until every enqueued job has finished
+func (q *InProcess) Wait() {
+	q.wg.Wait()
+}
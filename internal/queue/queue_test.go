@@ -0,0 +1,45 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/queue"
+)
+
+func TestInProcess(t *testing.T) {
+	var mu sync.Mutex
+	var kinds []string
+	q := queue.NewInProcess(func(ctx context.Context, job queue.Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		kinds = append(kinds, job.Kind)
+		if job.Kind == queue.KindSave {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	for _, kind := range []string{queue.KindSlackEvent, queue.KindSave, queue.KindTask} {
+		// A failing job does not fail the enqueue, since it runs after the response
+		if err := q.Enqueue(context.Background(), queue.Job{Kind: kind}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.Wait()
+
+	sort.Strings(kinds)
+	want := []string{queue.KindSave, queue.KindSlackEvent, queue.KindTask}
+	if len(kinds) != len(want) {
+		t.Fatalf("ran %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("ran %v, want %v", kinds, want)
+			break
+		}
+	}
+}
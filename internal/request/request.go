@@ -0,0 +1,44 @@
+// Package request reads and verifies the requests Slack sends to the Lambdas and the local server,
+// so that every entry point decodes and authenticates them the same way.
+package request
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/slack-go/slack"
+)
+
+// Body returns the raw request body. API Gateway encodes form bodies with base64
+func Body(r events.APIGatewayProxyRequest) ([]byte, error) {
+	if !r.IsBase64Encoded {
+		return []byte(r.Body), nil
+	}
+	return base64.StdEncoding.DecodeString(r.Body)
+}
+
+/*
+Verify validates the signature included in the Slack request to confirm that the request is from a legitimate source.
+The signature is computed over the raw body, so body must already be decoded from base64.
+*/
+func Verify(header http.Header, body []byte, signingSecret string) error {
+	sv, err := slack.NewSecretsVerifier(header, signingSecret)
+	if err != nil {
+		return err
+	}
+
+	if _, err := sv.Write(body); err != nil {
+		return err
+	}
+	return sv.Ensure()
+}
+
+// Header converts the headers of an API Gateway request to http.Header
+func Header(headers map[string]string) http.Header {
+	result := make(http.Header)
+	for key, value := range headers {
+		result.Set(key, value)
+	}
+	return result
+}
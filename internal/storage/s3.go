@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+)
+
+// S3 stores files in an S3 compatible bucket
+type S3 struct {
+	uploader *s3manager.Uploader
+	bucket   string
+	prefix   string
+	baseURL  string
+}
+
+// NewS3 returns a Storage that uploads files to the bucket with the default AWS credentials.
+// Set Endpoint to use an S3 compatible store such as MinIO or Cloudflare R2.
+func NewS3(cfg config.FileStorage) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("file_storage.bucket is required for s3")
+	}
+
+	awsConfig := aws.NewConfig()
+	if cfg.Region != "" {
+		awsConfig = awsConfig.WithRegion(cfg.Region)
+	}
+	if cfg.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(cfg.Endpoint).WithS3ForcePathStyle(true)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = "https://" + cfg.Bucket + ".s3." + aws.StringValue(sess.Config.Region) + ".amazonaws.com"
+	}
+
+	return &S3{
+		uploader: s3manager.NewUploader(sess),
+		bucket:   cfg.Bucket,
+		prefix:   cfg.Prefix,
+		baseURL:  baseURL,
+	}, nil
+}
+
+// Put uploads body to prefix/key. The bucket (or the CDN in front of it) must allow public reads.
+func (s *S3) Put(ctx context.Context, key string, contentType string, body io.Reader) (string, error) {
+	// Keys have no leading slash, even when the prefix is written like a path
+	key = strings.TrimPrefix(path.Join(s.prefix, key), "/")
+
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        body,
+	})
+	if err != nil {
+		return "", err
+	}
+	return PublicURL(s.baseURL, key), nil
+}
@@ -0,0 +1,74 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/storage"
+)
+
+func TestS3Put(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	tests := []struct {
+		name    string
+		prefix  string
+		key     string
+		wantKey string
+		wantURL string
+	}{
+		{name: "no prefix", key: "F1/a.txt", wantKey: "F1/a.txt", wantURL: "https://files.example.com/F1/a.txt"},
+		{name: "prefix", prefix: "slack", key: "F1/a.txt", wantKey: "slack/F1/a.txt", wantURL: "https://files.example.com/slack/F1/a.txt"},
+		{name: "prefix with slashes", prefix: "/slack/files/", key: "F1/a b.txt", wantKey: "slack/files/F1/a b.txt", wantURL: "https://files.example.com/slack/files/F1/a%20b.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// S3 compatible endpoint that records the uploaded object
+			var gotPath, gotContentType, gotBody string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				gotPath, gotContentType, gotBody = r.URL.Path, r.Header.Get("Content-Type"), string(body)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			s, err := storage.NewS3(config.FileStorage{
+				Bucket:   "bucket",
+				Region:   "us-east-1",
+				Endpoint: server.URL,
+				Prefix:   tt.prefix,
+				BaseURL:  "https://files.example.com",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			url, err := s.Put(context.Background(), tt.key, "text/plain", strings.NewReader("hello"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if url != tt.wantURL {
+				t.Errorf("url = %q, want %q", url, tt.wantURL)
+			}
+			if want := "/bucket/" + tt.wantKey; gotPath != want {
+				t.Errorf("uploaded to %q, want %q", gotPath, want)
+			}
+			if gotContentType != "text/plain" || gotBody != "hello" {
+				t.Errorf("uploaded %q (%s), want %q (text/plain)", gotBody, gotContentType, "hello")
+			}
+		})
+	}
+}
+
+func TestNewS3RequiresBucket(t *testing.T) {
+	if _, err := storage.NewS3(config.FileStorage{Region: "us-east-1"}); err == nil {
+		t.Error("err = nil, want a missing bucket error")
+	}
+}
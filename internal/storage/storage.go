@@ -0,0 +1,102 @@
+// Package storage publishes files copied from Slack, because Notion can only embed
+// files that are reachable through a public URL.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+)
+
+// Storage stores files and returns their public URL
+type Storage interface {
+	// Put stores body under key and returns the public URL of the object
+	Put(ctx context.Context, key string, contentType string, body io.Reader) (string, error)
+}
+
+// New returns the Storage configured in cfg, or nil when file copying is disabled
+func New(cfg config.FileStorage) (Storage, error) {
+	switch cfg.Type {
+	case config.FileStorageNone:
+		return nil, nil
+	case config.FileStorageLocal:
+		return NewLocal(cfg.Dir, cfg.BaseURL)
+	case config.FileStorageS3:
+		return NewS3(cfg)
+	default:
+		return nil, fmt.Errorf("unknown file storage: %s", cfg.Type)
+	}
+}
+
+// Local stores files in a directory that is served at BaseURL
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocal returns a Storage that writes files under dir
+func NewLocal(dir string, baseURL string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir, baseURL: baseURL}, nil
+}
+
+// Put writes body to dir/key
+func (l *Local) Put(ctx context.Context, key string, contentType string, body io.Reader) (string, error) {
+	path := filepath.Join(l.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, body); err != nil {
+		return "", err
+	}
+	return PublicURL(l.baseURL, key), f.Close()
+}
+
+// Handler serves the files of the directory. Directories are not listed, so a file is only
+// reachable through the URL of the page it is embedded in
+func (l *Local) Handler() http.Handler {
+	return http.FileServer(filesOnly{http.Dir(l.dir)})
+}
+
+// filesOnly is a file system whose directories do not exist
+type filesOnly struct {
+	fs http.FileSystem
+}
+
+func (f filesOnly) Open(name string) (http.File, error) {
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		file.Close()
+		return nil, os.ErrNotExist
+	}
+	return file, nil
+}
+
+// PublicURL joins baseURL and the escaped key
+func PublicURL(baseURL string, key string) string {
+	segments := strings.Split(key, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.Join(segments, "/")
+}
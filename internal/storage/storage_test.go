@@ -0,0 +1,95 @@
+package storage_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/storage"
+)
+
+func TestPublicURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		key     string
+		want    string
+	}{
+		{name: "plain", baseURL: "https://files.example.com", key: "T1/F1/image.png", want: "https://files.example.com/T1/F1/image.png"},
+		{name: "trailing slash", baseURL: "https://files.example.com/slack/", key: "F1/a.txt", want: "https://files.example.com/slack/F1/a.txt"},
+		{name: "spaces and reserved characters", baseURL: "https://files.example.com", key: "F1/a b?c#d%.txt", want: "https://files.example.com/F1/a%20b%3Fc%23d%25.txt"},
+		{name: "non-ASCII", baseURL: "https://files.example.com", key: "F1/議事録.pdf", want: "https://files.example.com/F1/%E8%AD%B0%E4%BA%8B%E9%8C%B2.pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := storage.PublicURL(tt.baseURL, tt.key); got != tt.want {
+				t.Errorf("PublicURL(%q, %q) = %q, want %q", tt.baseURL, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalPut(t *testing.T) {
+	dir := t.TempDir()
+	local, err := storage.NewLocal(dir, "http://localhost/files")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url, err := local.Put(context.Background(), "F1/a b.txt", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "http://localhost/files/F1/a%20b.txt"; url != want {
+		t.Errorf("url = %q, want %q", url, want)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "F1", "a b.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "hello" {
+		t.Errorf("content = %q, want %q", content, "hello")
+	}
+}
+
+func TestLocalHandler(t *testing.T) {
+	local, err := storage.NewLocal(t.TempDir(), "http://localhost/files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := local.Put(context.Background(), "F1/a.txt", "text/plain", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.StripPrefix("/files/", local.Handler()))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{name: "file", path: "/files/F1/a.txt", wantStatus: http.StatusOK},
+		{name: "root", path: "/files/", wantStatus: http.StatusNotFound},
+		{name: "directory", path: "/files/F1/", wantStatus: http.StatusNotFound},
+		{name: "directory without slash", path: "/files/F1", wantStatus: http.StatusNotFound},
+		{name: "missing file", path: "/files/F1/b.txt", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("GET %s = %d, want %d", tt.path, res.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+)
+
+// Extraction is the structured content extracted from a thread
+type Extraction struct {
+	Decisions     []string     `json:"decisions"`
+	ActionItems   []ActionItem `json:"action_items"`
+	OpenQuestions []string     `json:"open_questions"`
+}
+
+// ActionItem is a task agreed in the thread
+type ActionItem struct {
+	Task string `json:"task"`
+	// Assignee is a Slack mention such as "<@U0123456789>", a name, or empty when nobody was assigned
+	Assignee string `json:"assignee"`
+}
+
+// Empty reports whether nothing was extracted
+func (e Extraction) Empty() bool {
+	return len(e.Decisions) == 0 && len(e.ActionItems) == 0 && len(e.OpenQuestions) == 0
+}
+
+// Extractor extracts decisions, action items and open questions from a thread
+type Extractor interface {
+	Extract(ctx context.Context, thread Thread, prompt config.Prompt) (Extraction, error)
+}
+
+const extractSystemPrompt = "You extract decisions, action items and unresolved questions from Slack threads and answer only with JSON."
+
+const extractInstructions = `Read the Slack thread below and answer with a JSON object of this shape, without any other text:
+{"decisions": ["..."], "action_items": [{"task": "...", "assignee": "<@USER_ID>"}], "open_questions": ["..."]}
+
+- decisions: what was agreed or decided
+- action_items: tasks someone has to do. assignee is the <@USER_ID> of the author responsible for it, or "" when nobody was assigned
+- open_questions: questions that were not answered
+Use empty arrays when there is nothing to report. Write the values in %s.
+
+`
+
+// Extract asks the model for the decisions, action items and open questions of the thread as JSON.
+// Threads that do not fit in one request are extracted chunk by chunk and the results are concatenated
+func (s *OpenAI) Extract(ctx context.Context, thread Thread, prompt config.Prompt) (Extraction, error) {
+	var extraction Extraction
+	chunks := Chunk(thread.Messages, s.maxInputTokens)
+	for i, chunk := range chunks {
+		e, err := s.extract(ctx, chunk, prompt.Language)
+		if err != nil {
+			if len(chunks) > 1 {
+				err = fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+			}
+			return Extraction{}, err
+		}
+		extraction.Decisions = append(extraction.Decisions, e.Decisions...)
+		extraction.ActionItems = append(extraction.ActionItems, e.ActionItems...)
+		extraction.OpenQuestions = append(extraction.OpenQuestions, e.OpenQuestions...)
+	}
+	return extraction, nil
+}
+
+func (s *OpenAI) extract(ctx context.Context, messages []Message, language string) (Extraction, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, extractInstructions, language)
+	for _, m := range messages {
+		if m.UserID != "" {
+			fmt.Fprintf(&sb, "%s (<@%s>) %s: %s\n", m.Author, m.UserID, m.Time, m.Text)
+		} else {
+			fmt.Fprintf(&sb, "%s %s: %s\n", m.Author, m.Time, m.Text)
+		}
+	}
+
+	content, err := s.chat(ctx, extractSystemPrompt, sb.String())
+	if err != nil {
+		return Extraction{}, err
+	}
+	return ParseExtraction(content)
+}
+
+// ParseExtraction parses the JSON answer of the model.
+// Text around the JSON object, such as a markdown code fence, is ignored
+func ParseExtraction(content string) (Extraction, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return Extraction{}, errors.New("no JSON object in the answer")
+	}
+
+	var e Extraction
+	if err := json.Unmarshal([]byte(content[start:end+1]), &e); err != nil {
+		return Extraction{}, fmt.Errorf("invalid JSON in the answer: %w", err)
+	}
+	return e, nil
+}
@@ -0,0 +1,87 @@
+package summary_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/summary"
+)
+
+func TestParseExtraction(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		want      summary.Extraction
+		wantEmpty bool
+		// wantErr is a substring of the error, or empty when the answer is valid
+		wantErr string
+	}{
+		{
+			name:    "plain JSON",
+			content: `{"decisions": ["ship on Friday"], "action_items": [{"task": "write the release note", "assignee": "<@U1>"}], "open_questions": ["who announces it?"]}`,
+			want: summary.Extraction{
+				Decisions:     []string{"ship on Friday"},
+				ActionItems:   []summary.ActionItem{{Task: "write the release note", Assignee: "<@U1>"}},
+				OpenQuestions: []string{"who announces it?"},
+			},
+		},
+		{
+			name:    "code fence",
+			content: "Here you are:\n```json\n{\"decisions\": [\"use Go\"], \"action_items\": [], \"open_questions\": []}\n```",
+			want:    summary.Extraction{Decisions: []string{"use Go"}, ActionItems: []summary.ActionItem{}, OpenQuestions: []string{}},
+		},
+		{
+			name:    "unassigned action item",
+			content: `{"action_items": [{"task": "book a room", "assignee": ""}]}`,
+			want:    summary.Extraction{ActionItems: []summary.ActionItem{{Task: "book a room"}}},
+		},
+		{
+			name:      "empty extraction",
+			content:   `{"decisions": [], "action_items": [], "open_questions": []}`,
+			want:      summary.Extraction{Decisions: []string{}, ActionItems: []summary.ActionItem{}, OpenQuestions: []string{}},
+			wantEmpty: true,
+		},
+		{
+			name:      "empty object",
+			content:   `{}`,
+			wantEmpty: true,
+		},
+		{
+			name:    "invalid JSON",
+			content: `{"decisions": ["unterminated}`,
+			wantErr: "invalid JSON in the answer",
+		},
+		{
+			name:    "wrong shape",
+			content: `{"decisions": "not a list"}`,
+			wantErr: "invalid JSON in the answer",
+		},
+		{
+			name:    "no JSON",
+			content: "Nothing was decided.",
+			wantErr: "no JSON object in the answer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := summary.ParseExtraction(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extraction = %+v, want %+v", got, tt.want)
+			}
+			if got.Empty() != tt.wantEmpty {
+				t.Errorf("Empty() = %v, want %v", got.Empty(), tt.wantEmpty)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ChatClient is the subset of *openai.Client used by OpenAI
+type ChatClient interface {
+	CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
+
+// DefaultMaxInputTokens is the estimated tokens of messages sent in one request when
+// config.Summarizer.MaxInputTokens is not set. It leaves room for the prompt and the answer in the
+// 4k context window of gpt-3.5-turbo
+const DefaultMaxInputTokens = 3000
+
+// maxReduceDepth bounds how many times the summaries of chunks are summarized again
+const maxReduceDepth = 5
+
+// OpenAI summarizes threads with the chat completion API of OpenAI or any compatible endpoint.
+// Threads that do not fit in one request are summarized chunk by chunk, then the summaries
+// of the chunks are summarized again (map-reduce)
+type OpenAI struct {
+	client         ChatClient
+	model          string
+	maxInputTokens int
+}
+
+// NewOpenAI returns an OpenAI summarizer configured by cfg. The model defaults to gpt-3.5-turbo
+func NewOpenAI(client ChatClient, cfg config.Summarizer) *OpenAI {
+	s := &OpenAI{client: client, model: cfg.Model, maxInputTokens: cfg.MaxInputTokens}
+	if s.model == "" {
+		s.model = openai.GPT3Dot5Turbo
+	}
+	if s.maxInputTokens <= 0 {
+		s.maxInputTokens = DefaultMaxInputTokens
+	}
+	return s
+}
+
+// Summarize summarizes the thread messages by ChatGPT
+func (s *OpenAI) Summarize(ctx context.Context, thread Thread, prompt config.Prompt) (string, error) {
+	return s.summarizeMessages(ctx, thread, prompt, thread.Messages, 0)
+}
+
+func (s *OpenAI) summarizeMessages(ctx context.Context, thread Thread, prompt config.Prompt, messages []Message, depth int) (string, error) {
+	chunks := Chunk(messages, s.maxInputTokens)
+	if len(chunks) == 0 {
+		return "", nil
+	}
+	if len(chunks) == 1 {
+		return s.complete(ctx, thread, prompt, chunks[0])
+	}
+	if depth >= maxReduceDepth {
+		return "", fmt.Errorf("thread is too long to summarize: %d chunks left after %d reductions", len(chunks), depth)
+	}
+
+	summaries := make([]Message, 0, len(chunks))
+	for i, chunk := range chunks {
+		summary, err := s.complete(ctx, thread, prompt, chunk)
+		if err != nil {
+			return "", fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+		summaries = append(summaries, Message{Text: summary})
+	}
+	return s.summarizeMessages(ctx, thread, prompt, summaries, depth+1)
+}
+
+func (s *OpenAI) complete(ctx context.Context, thread Thread, prompt config.Prompt, messages []Message) (string, error) {
+	system, instructions, err := renderPrompt(prompt, thread, messages)
+	if err != nil {
+		return "", err
+	}
+
+	return s.chat(ctx, system, instructions)
+}
+
+// chat sends the system and user messages and returns the content of the first choice
+func (s *OpenAI) chat(ctx context.Context, system string, user string) (string, error) {
+	resp, err := s.client.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: s.model,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: system,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: user,
+				},
+			},
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", errors.New("openai returned no choices")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
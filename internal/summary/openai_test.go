@@ -0,0 +1,141 @@
+package summary_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/summary"
+	"github.com/sashabaranov/go-openai"
+)
+
+// chatClient replies with reply(n) to the nth request and records the user messages
+type chatClient struct {
+	reply    func(n int) string
+	err      error
+	requests []string
+}
+
+func (c *chatClient) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	c.requests = append(c.requests, request.Messages[1].Content)
+	if c.err != nil {
+		return openai.ChatCompletionResponse{}, c.err
+	}
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: c.reply(len(c.requests))}},
+		},
+	}, nil
+}
+
+// prompt renders one message per line, so the requests show which messages were sent
+var prompt = config.Prompt{
+	System:       "summarize",
+	Instructions: "{{range .Messages}}{{.Text}}\n{{end}}",
+}
+
+func TestOpenAISummarize(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []summary.Message
+		reply    func(n int) string
+		err      error
+		want     string
+		// wantRequests is the user message of every request in order
+		wantRequests []string
+		wantErr      string
+	}{
+		{
+			name:  "empty thread",
+			reply: func(n int) string { return "unused" },
+		},
+		{
+			name:         "one request",
+			messages:     messages("aaaa", "bbbb"),
+			reply:        func(n int) string { return "final" },
+			want:         "final",
+			wantRequests: []string{"aaaa\nbbbb\n"},
+		},
+		{
+			name:     "map-reduce",
+			messages: messages("aaaa", "bbbb", "cccc"),
+			reply:    func(n int) string { return fmt.Sprintf("s%d", n) },
+			want:     "s3",
+			// the two chunks are summarized, then their summaries together
+			wantRequests: []string{"aaaa\nbbbb\n", "cccc\n", "s1\ns2\n"},
+		},
+		{
+			name:     "depth cutoff",
+			messages: messages("aaaa", "bbbb", "cccc"),
+			// every summary is as long as the budget, so the chunks never shrink
+			reply:   func(n int) string { return strings.Repeat("x", 24) },
+			wantErr: "thread is too long to summarize: 2 chunks left after 5 reductions",
+		},
+		{
+			name:     "chunk error",
+			messages: messages("aaaa", "bbbb", "cccc"),
+			err:      errors.New("rate limited"),
+			wantErr:  "chunk 1/2: rate limited",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &chatClient{reply: tt.reply, err: tt.err}
+			s := summary.NewOpenAI(client, config.Summarizer{MaxInputTokens: 10})
+
+			got, err := s.Summarize(context.Background(), summary.Thread{Messages: tt.messages}, prompt)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("summary = %q, want %q", got, tt.want)
+			}
+			if strings.Join(client.requests, "|") != strings.Join(tt.wantRequests, "|") {
+				t.Errorf("requests = %q, want %q", client.requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestOpenAISummarizeStopsAtMaxReduceDepth(t *testing.T) {
+	client := &chatClient{reply: func(n int) string { return strings.Repeat("x", 24) }}
+	s := summary.NewOpenAI(client, config.Summarizer{MaxInputTokens: 10})
+
+	if _, err := s.Summarize(context.Background(), summary.Thread{Messages: messages("aaaa", "bbbb", "cccc")}, prompt); err == nil {
+		t.Fatal("err = nil, want an error")
+	}
+	// 2 chunks of the messages, then 2 chunks of the summaries at every reduction but the last
+	if want := 2 + 2*4; len(client.requests) != want {
+		t.Errorf("requests = %d, want %d", len(client.requests), want)
+	}
+}
+
+func TestOpenAISummarizeDefaultPrompt(t *testing.T) {
+	client := &chatClient{reply: func(n int) string { return "summary" }}
+	s := summary.NewOpenAI(client, config.Summarizer{})
+	defaults := config.Prompt{
+		System:       config.DefaultPromptSystem,
+		Instructions: config.DefaultPromptInstructions,
+		Language:     "English",
+		Length:       400,
+	}
+
+	if _, err := s.Summarize(context.Background(), summary.Thread{Messages: messages("aaaa", "bbbb")}, defaults); err != nil {
+		t.Fatal(err)
+	}
+	// Only the language and the length of the prompt decide the output
+	want := "Summarize the following conversation in English within 400 characters.\n\naaaa\n\nbbbb\n\n"
+	if len(client.requests) != 1 || client.requests[0] != want {
+		t.Errorf("requests = %q, want %q", client.requests, want)
+	}
+}
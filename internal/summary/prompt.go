@@ -0,0 +1,38 @@
+package summary
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+)
+
+// renderPrompt executes the system and instruction templates of prompt for the messages of thread
+func renderPrompt(prompt config.Prompt, thread Thread, messages []Message) (system string, instructions string, err error) {
+	data := config.PromptData{
+		Channel:      thread.Channel,
+		Participants: thread.Participants,
+		Messages:     messages,
+		Language:     prompt.Language,
+		Length:       prompt.Length,
+	}
+	if system, err = execute("system", prompt.System, data); err != nil {
+		return "", "", err
+	}
+	if instructions, err = execute("instructions", prompt.Instructions, data); err != nil {
+		return "", "", err
+	}
+	return system, instructions, nil
+}
+
+func execute(name string, text string, data config.PromptData) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
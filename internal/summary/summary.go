@@ -0,0 +1,62 @@
+// Package summary summarizes Slack threads for the archived Notion pages.
+package summary
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Thread is the thread to summarize, with mentions and names already resolved
+type Thread struct {
+	// Channel is the channel name
+	Channel string
+	// Participants are the names of the authors in order of appearance
+	Participants []string
+	Messages     []Message
+}
+
+// Message is a message of Thread. It is the message of the prompt templates as well
+type Message = config.PromptMessage
+
+// Summarizer summarizes the messages of a thread with the prompt
+type Summarizer interface {
+	Summarize(ctx context.Context, thread Thread, prompt config.Prompt) (string, error)
+}
+
+// Noop does not summarize. The summary section is omitted from the page
+type Noop struct{}
+
+// Summarize returns an empty summary
+func (Noop) Summarize(ctx context.Context, thread Thread, prompt config.Prompt) (string, error) {
+	return "", nil
+}
+
+// FromConfig builds the summarizers configured in cfg keyed by name. "none" is always available.
+func FromConfig(cfg *config.Config) (map[string]Summarizer, error) {
+	summarizers := map[string]Summarizer{config.SummarizerNone: Noop{}}
+	for name, s := range cfg.Summarizers {
+		switch s.Type {
+		case config.SummarizerNone:
+			summarizers[name] = Noop{}
+		case config.SummarizerOpenAI:
+			apiKey := s.APIKey
+			if apiKey == "" {
+				apiKey = cfg.OpenAIAPIKey
+			}
+			if apiKey == "" {
+				return nil, fmt.Errorf("summarizers.%s: %s is required", name, config.OpenAIAPIKey)
+			}
+			summarizers[name] = NewOpenAI(openai.NewClient(apiKey), s)
+		case config.SummarizerOpenAICompatible:
+			clientConfig := openai.DefaultConfig(s.APIKey)
+			clientConfig.BaseURL = s.BaseURL
+			summarizers[name] = NewOpenAI(openai.NewClientWithConfig(clientConfig), s)
+		default:
+			return nil, fmt.Errorf("summarizers.%s: unknown type %q", name, s.Type)
+		}
+	}
+	return summarizers, nil
+}
@@ -0,0 +1,81 @@
+package summary
+
+import "unicode/utf8"
+
+// messageOverheadTokens is added per message for the separators between messages
+const messageOverheadTokens = 4
+
+// EstimateTokens roughly estimates the number of tokens of text without a tokenizer:
+// about 4 ASCII characters per token, and one token per character otherwise (e.g. Japanese)
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// Chunk groups messages in order so that the estimated tokens of every chunk stay within maxTokens.
+// A message longer than maxTokens is split into several messages of the same author
+func Chunk(messages []Message, maxTokens int) [][]Message {
+	var chunks [][]Message
+	var current []Message
+	currentTokens := 0
+	flush := func() {
+		if len(current) > 0 {
+			chunks = append(chunks, current)
+			current, currentTokens = nil, 0
+		}
+	}
+
+	for _, message := range messages {
+		overhead := EstimateTokens(message.UserID) + EstimateTokens(message.Author) + EstimateTokens(message.Time) + messageOverheadTokens
+		for _, part := range splitText(message.Text, maxTokens-overhead) {
+			tokens := EstimateTokens(part) + overhead
+			if currentTokens+tokens > maxTokens {
+				flush()
+			}
+			m := message
+			m.Text = part
+			current = append(current, m)
+			currentTokens += tokens
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitText splits text into parts of at most maxTokens estimated tokens
+func splitText(text string, maxTokens int) []string {
+	if maxTokens < 1 {
+		maxTokens = 1
+	}
+	if EstimateTokens(text) <= maxTokens {
+		return []string{text}
+	}
+
+	var parts []string
+	start, ascii, other := 0, 0, 0
+	for i, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+		if (ascii+3)/4+other > maxTokens {
+			parts = append(parts, text[start:i])
+			start = i
+			ascii, other = 0, 0
+			if r < utf8.RuneSelf {
+				ascii++
+			} else {
+				other++
+			}
+		}
+	}
+	return append(parts, text[start:])
+}
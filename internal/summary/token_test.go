@@ -0,0 +1,109 @@
+package summary_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/summary"
+)
+
+// messages returns a message for every text, without author or time so that the overhead is 4 tokens
+func messages(texts ...string) []summary.Message {
+	var m []summary.Message
+	for _, text := range texts {
+		m = append(m, summary.Message{Text: text})
+	}
+	return m
+}
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name      string
+		messages  []summary.Message
+		maxTokens int
+		// want is the texts of every chunk
+		want [][]string
+	}{
+		{
+			name:      "empty thread",
+			maxTokens: 10,
+			want:      nil,
+		},
+		{
+			name:      "fits in one chunk",
+			messages:  messages("aaaa", "bbbb"),
+			maxTokens: 10,
+			want:      [][]string{{"aaaa", "bbbb"}},
+		},
+		{
+			name:      "chunk boundary",
+			messages:  messages("aaaa", "bbbb", "cccc"),
+			maxTokens: 10,
+			want:      [][]string{{"aaaa", "bbbb"}, {"cccc"}},
+		},
+		{
+			name:      "one message over the budget",
+			messages:  messages(strings.Repeat("a", 60)),
+			maxTokens: 10,
+			want:      [][]string{{strings.Repeat("a", 24)}, {strings.Repeat("a", 24)}, {strings.Repeat("a", 12)}},
+		},
+		{
+			name:      "multibyte split",
+			messages:  messages("あいうえおかきくけこ"),
+			maxTokens: 9,
+			want:      [][]string{{"あいうえお"}, {"かきくけこ"}},
+		},
+		{
+			name:      "budget smaller than the overhead",
+			messages:  messages("ab"),
+			maxTokens: 2,
+			want:      [][]string{{"ab"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := summary.Chunk(tt.messages, tt.maxTokens)
+
+			var got [][]string
+			for _, chunk := range chunks {
+				var texts []string
+				for _, m := range chunk {
+					if !utf8.ValidString(m.Text) {
+						t.Errorf("part %q is not valid UTF-8", m.Text)
+					}
+					texts = append(texts, m.Text)
+				}
+				got = append(got, texts)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Chunk = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if strings.Join(got[i], "|") != strings.Join(tt.want[i], "|") {
+					t.Errorf("chunk %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "", want: 0},
+		{text: "abcd", want: 1},
+		{text: "abcde", want: 2},
+		{text: "日本語", want: 3},
+		{text: "ab日本", want: 3},
+	}
+
+	for _, tt := range tests {
+		if got := summary.EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
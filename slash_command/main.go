@@ -1,98 +1,116 @@
 package main
 
 import (
-	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/modal"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/request"
 	"github.com/slack-go/slack"
 )
 
 // Response is the response struct for the lambda function
 type Response events.APIGatewayProxyResponse
 
+var cfg *config.Config
+
 // Handler is the main function for the lambda function
 func Handler(r events.APIGatewayProxyRequest) (Response, error) {
+	body, err := request.Body(r)
+	if err != nil {
+		log.Printf("[ERROR] Failed to decode base64 encoded payload: %v", err)
+		return Response{StatusCode: 200}, nil
+	}
 
-	if err := slackRequestVerifier(r); err != nil {
+	if err := request.Verify(request.Header(r.Headers), body, cfg.SlackSigningSecret); err != nil {
 		log.Printf("[ERROR] failed to verify payload: %v", err)
 		return Response{StatusCode: 200}, nil
 	}
 	log.Printf("[INFO] Done slackRequestVerifier")
 
-	payload := buildPayloadMap(r.Body)
-
-	inputModal := createInputModal()
-	log.Printf("[INFO] Done createInputModal")
-
-	slackClient := slack.New(os.Getenv("SLACK_TOKEN"))
-	if _, err := slackClient.OpenView(payload["trigger_id"], *inputModal); err != nil {
-		log.Printf("[ERROR] failed to open modal: %v", err)
+	command, err := parseSlashCommand(body)
+	if err != nil {
+		log.Printf("[ERROR] Failed to parse slash command: %v", err)
 		return Response{StatusCode: 200}, nil
 	}
-
-	return Response{StatusCode: 200}, nil
+	log.Printf("[INFO] command: %s %s", command.Command, command.Text)
+
+	subcommand, _, _ := strings.Cut(strings.TrimSpace(command.Text), " ")
+	switch strings.ToLower(subcommand) {
+	case "", "add", "task":
+		return openTaskModal(command)
+	case "help":
+		return ephemeral(usage(command.Command))
+	default:
+		return ephemeral(fmt.Sprintf("不明なコマンドです: %s\n%s", subcommand, usage(command.Command)))
+	}
 }
 
-func buildPayloadMap(body string) map[string]string {
-	dec, _ := base64.StdEncoding.DecodeString(body)
-	parameters, _ := url.QueryUnescape(string(dec))
+func openTaskModal(command slack.SlashCommand) (Response, error) {
+	inputModal := modal.NewTaskModal(cfg)
+	log.Printf("[INFO] Done NewTaskModal")
 
-	payload := make(map[string]string)
-	for _, s := range strings.Split(parameters, "&") {
-		arr := strings.Split(s, "=")
-		payload[arr[0]] = arr[1]
+	slackClient := slack.New(cfg.SlackToken)
+	if _, err := slackClient.OpenView(command.TriggerID, *inputModal); err != nil {
+		log.Printf("[ERROR] failed to open modal: %v", err)
+		return ephemeral("モーダルを開けませんでした。時間をおいて再度お試しください")
 	}
 
-	return payload
+	return Response{StatusCode: 200}, nil
 }
 
-func slackRequestVerifier(r events.APIGatewayProxyRequest) error {
-	header := http.Header{}
-	for k, v := range r.Headers {
-		header.Set(k, v)
-	}
-
-	sv, err := slack.NewSecretsVerifier(header, os.Getenv("SLACK_SIGNING_SECRET"))
-	if err != nil {
-		return err
-	}
+func usage(command string) string {
+	return fmt.Sprintf("使い方:\n`%[1]s` または `%[1]s add`: Notionにタスクを追加する\n`%[1]s help`: このヘルプを表示する", command)
+}
 
-	body, err := base64.StdEncoding.DecodeString(r.Body)
+// ephemeral responds with a message only the user who ran the command can see
+func ephemeral(text string) (Response, error) {
+	body, err := json.Marshal(map[string]string{"response_type": slack.ResponseTypeEphemeral, "text": text})
 	if err != nil {
-		return err
+		return Response{StatusCode: 200}, nil
 	}
-
-	sv.Write(body)
-	return sv.Ensure()
+	return Response{
+		Body:       string(body),
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}, nil
 }
 
-func createInputModal() *slack.ModalViewRequest {
-	titleInputLabel := slack.NewTextBlockObject("plain_text", "タイトル", true, false)
-	titleInputElement := slack.NewPlainTextInputBlockElement(titleInputLabel, "title")
-	titleInput := slack.NewInputBlock("notion_title", titleInputLabel, titleInputElement)
-
-	contextInputLabel := slack.NewTextBlockObject("plain_text", "タスク内容", true, false)
-	contextInputElement := slack.NewPlainTextInputBlockElement(contextInputLabel, "context")
-	contextInputElement.Multiline = true
-	contextInput := slack.NewInputBlock("notion_context", contextInputLabel, contextInputElement)
-
-	inputModal := &slack.ModalViewRequest{
-		Type:   slack.ViewType("modal"),
-		Title:  slack.NewTextBlockObject("plain_text", "NotionのDBに追加する", true, false),
-		Blocks: slack.Blocks{BlockSet: []slack.Block{titleInput, contextInput}},
-		Close:  slack.NewTextBlockObject("plain_text", "キャンセル", true, false),
-		Submit: slack.NewTextBlockObject("plain_text", "追加", true, false),
+// parseSlashCommand decodes the application/x-www-form-urlencoded body of the slash command
+func parseSlashCommand(body []byte) (slack.SlashCommand, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return slack.SlashCommand{}, err
 	}
 
-	return inputModal
+	return slack.SlashCommand{
+		Token:          values.Get("token"),
+		TeamID:         values.Get("team_id"),
+		TeamDomain:     values.Get("team_domain"),
+		EnterpriseID:   values.Get("enterprise_id"),
+		EnterpriseName: values.Get("enterprise_name"),
+		ChannelID:      values.Get("channel_id"),
+		ChannelName:    values.Get("channel_name"),
+		UserID:         values.Get("user_id"),
+		UserName:       values.Get("user_name"),
+		Command:        values.Get("command"),
+		Text:           values.Get("text"),
+		ResponseURL:    values.Get("response_url"),
+		TriggerID:      values.Get("trigger_id"),
+		APIAppID:       values.Get("api_app_id"),
+	}, nil
 }
 
 func main() {
+	cfg = config.MustLoad(config.SlackToken, config.SlackSigningSecret)
 	lambda.Start(Handler)
 }
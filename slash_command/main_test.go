@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/furuich-kotaro/go-slack-to-notion/internal/config"
+)
+
+const signingSecret = "secret"
+
+// signedRequest returns the API Gateway request of body signed with signingSecret
+func signedRequest(body string, encode bool) events.APIGatewayProxyRequest {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+
+	r := events.APIGatewayProxyRequest{
+		Headers: map[string]string{
+			"X-Slack-Request-Timestamp": timestamp,
+			"X-Slack-Signature":         "v0=" + hex.EncodeToString(mac.Sum(nil)),
+		},
+		Body: body,
+	}
+	if encode {
+		r.Body = base64.StdEncoding.EncodeToString([]byte(body))
+		r.IsBase64Encoded = true
+	}
+	return r
+}
+
+func TestParseSlashCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantCommand string
+		wantText    string
+		wantTrigger string
+		wantURL     string
+	}{
+		{
+			name:        "plain",
+			body:        "command=%2Fnotion&text=add&trigger_id=123.456",
+			wantCommand: "/notion",
+			wantText:    "add",
+			wantTrigger: "123.456",
+		},
+		{
+			name:        "values containing =",
+			body:        "command=%2Fnotion&text=a%3Db%3Dc&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%3Fa%3Db",
+			wantCommand: "/notion",
+			wantText:    "a=b=c",
+			wantURL:     "https://hooks.slack.com/commands?a=b",
+		},
+		{
+			name:        "unescaped = in value",
+			body:        "command=/notion&text=x=y",
+			wantCommand: "/notion",
+			wantText:    "x=y",
+		},
+		{
+			name:        "empty values",
+			body:        "command=%2Fnotion&text=&trigger_id",
+			wantCommand: "/notion",
+		},
+		{
+			name:        "plus as space",
+			body:        "command=%2Fnotion&text=help+me",
+			wantCommand: "/notion",
+			wantText:    "help me",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, err := parseSlashCommand([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if command.Command != tt.wantCommand {
+				t.Errorf("Command = %q, want %q", command.Command, tt.wantCommand)
+			}
+			if command.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", command.Text, tt.wantText)
+			}
+			if command.TriggerID != tt.wantTrigger {
+				t.Errorf("TriggerID = %q, want %q", command.TriggerID, tt.wantTrigger)
+			}
+			if command.ResponseURL != tt.wantURL {
+				t.Errorf("ResponseURL = %q, want %q", command.ResponseURL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestParseSlashCommandInvalid(t *testing.T) {
+	if _, err := parseSlashCommand([]byte("text=%zz")); err == nil {
+		t.Error("err = nil, want an invalid escape error")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	cfg = &config.Config{SlackSigningSecret: signingSecret}
+
+	tests := []struct {
+		name    string
+		request events.APIGatewayProxyRequest
+		// wantText is a substring of the ephemeral response, or empty when the request is only acknowledged
+		wantText string
+	}{
+		{
+			name:     "help",
+			request:  signedRequest("command=%2Fnotion&text=help", false),
+			wantText: "使い方:\n`/notion`",
+		},
+		{
+			name:     "help in a base64 body",
+			request:  signedRequest("command=%2Fnotion&text=help", true),
+			wantText: "使い方:\n`/notion`",
+		},
+		{
+			name:     "help in upper case with arguments",
+			request:  signedRequest("command=%2Fnotion&text=+HELP+add", false),
+			wantText: "使い方:",
+		},
+		{
+			name:     "unknown subcommand",
+			request:  signedRequest("command=%2Fnotion&text=delete+all", true),
+			wantText: "不明なコマンドです: delete\n使い方:",
+		},
+		{
+			name: "invalid signature",
+			request: func() events.APIGatewayProxyRequest {
+				r := signedRequest("command=%2Fnotion&text=help", false)
+				r.Body = "command=%2Fnotion&text=unknown"
+				return r
+			}(),
+		},
+		{
+			name: "invalid base64",
+			request: func() events.APIGatewayProxyRequest {
+				r := signedRequest("command=%2Fnotion&text=help", true)
+				r.Body = "!" + r.Body
+				return r
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Handler(tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != 200 {
+				t.Fatalf("StatusCode = %d, want 200", res.StatusCode)
+			}
+
+			if tt.wantText == "" {
+				if res.Body != "" {
+					t.Errorf("Body = %q, want empty", res.Body)
+				}
+				return
+			}
+			var body struct {
+				ResponseType string `json:"response_type"`
+				Text         string `json:"text"`
+			}
+			if err := json.Unmarshal([]byte(res.Body), &body); err != nil {
+				t.Fatalf("Body = %q: %v", res.Body, err)
+			}
+			if body.ResponseType != "ephemeral" {
+				t.Errorf("response_type = %q, want ephemeral", body.ResponseType)
+			}
+			if !strings.Contains(body.Text, tt.wantText) {
+				t.Errorf("text = %q, want %q", body.Text, tt.wantText)
+			}
+		})
+	}
+}